@@ -33,4 +33,16 @@ const (
 	ENUM_SCHEDULE_STATUS_PENDING  = "pending"
 	ENUM_SCHEDULE_STATUS_APPROVED = "approved"
 	ENUM_SCHEDULE_STATUS_REJECTED = "rejected"
+
+	ENUM_OUTBOX_STATUS_PENDING = "pending"
+	ENUM_OUTBOX_STATUS_SENT    = "sent"
+
+	ENUM_MESSAGE_ACTION_EDIT   = "edit"
+	ENUM_MESSAGE_ACTION_DELETE = "delete"
+
+	ENUM_NOTIFICATION_PRIORITY_NORMAL = "normal"
+	ENUM_NOTIFICATION_PRIORITY_HIGH   = "high"
+
+	ENUM_QUEUE_SUMMARY_TASK   = "summary_task"
+	ENUM_QUEUE_SUMMARY_RESULT = "summary_result"
 )
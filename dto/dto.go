@@ -1,7 +1,10 @@
 package dto
 
 import (
+	"encoding/json"
 	"errors"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/Amierza/chat-service/entity"
@@ -43,11 +46,17 @@ const (
 	NOT_FOUND          = "not found"
 
 	// Custom
-	MESSAGE_FAILED_START_SESSION = "failed start session"
-	MESSAGE_FAILED_JOIN_SESSION  = "failed join session"
-	MESSAGE_FAILED_LEAVE_SESSION = "failed leave session"
-	MESSAGE_FAILED_END_SESSION   = "failed end session"
-	MESSAGE_FAILED_SEND_MESSAGE  = "failed send message"
+	MESSAGE_FAILED_START_SESSION       = "failed start session"
+	MESSAGE_FAILED_JOIN_SESSION        = "failed join session"
+	MESSAGE_FAILED_LEAVE_SESSION       = "failed leave session"
+	MESSAGE_FAILED_END_SESSION         = "failed end session"
+	MESSAGE_FAILED_SEND_MESSAGE        = "failed send message"
+	MESSAGE_FAILED_EDIT_MESSAGE        = "failed edit message"
+	MESSAGE_FAILED_DELETE_MESSAGE      = "failed delete message"
+	MESSAGE_FAILED_MARK_READ           = "failed mark read"
+	MESSAGE_FAILED_PIN_MESSAGE         = "failed pin message"
+	MESSAGE_FAILED_UNPIN_MESSAGE       = "failed unpin message"
+	MESSAGE_FAILED_GET_PINNED_MESSAGES = "failed get pinned messages"
 
 	// ====================================== Success ======================================
 
@@ -67,11 +76,17 @@ const (
 	SUCCESS_GET_PROFILE = "success to get profile"
 
 	// Custom
-	MESSAGE_SUCCESS_START_SESSION = "success start session"
-	MESSAGE_SUCCESS_JOIN_SESSION  = "success join session"
-	MESSAGE_SUCCESS_LEAVE_SESSION = "success leave session"
-	MESSAGE_SUCCESS_END_SESSION   = "success end session"
-	MESSAGE_SUCCESS_SEND_MESSAGE  = "success send message"
+	MESSAGE_SUCCESS_START_SESSION       = "success start session"
+	MESSAGE_SUCCESS_JOIN_SESSION        = "success join session"
+	MESSAGE_SUCCESS_LEAVE_SESSION       = "success leave session"
+	MESSAGE_SUCCESS_END_SESSION         = "success end session"
+	MESSAGE_SUCCESS_SEND_MESSAGE        = "success send message"
+	MESSAGE_SUCCESS_EDIT_MESSAGE        = "success edit message"
+	MESSAGE_SUCCESS_DELETE_MESSAGE      = "success delete message"
+	MESSAGE_SUCCESS_PIN_MESSAGE         = "success pin message"
+	MESSAGE_SUCCESS_UNPIN_MESSAGE       = "success unpin message"
+	MESSAGE_SUCCESS_GET_PINNED_MESSAGES = "success get pinned messages"
+	MESSAGE_SUCCESS_MARK_READ           = "success mark read"
 )
 
 var (
@@ -86,6 +101,16 @@ var (
 	ErrInvalidFileType    = errors.New("only jpg/jpeg/png allowed")
 	ErrSaveFile           = errors.New("failed save file")
 	ErrCreateFolderAssets = errors.New("failed create folder assets")
+	ErrCreateAttachment   = errors.New("failed create attachment")
+
+	// Attachment
+	ErrGetAttachments        = errors.New("failed get attachments")
+	ErrAttachmentNotFound    = errors.New("failed attachment not found")
+	ErrNotAttachmentOwner    = errors.New("failed attachment belongs to another user")
+	ErrAttachmentAlreadyUsed = errors.New("failed attachment already used in another message")
+	ErrTooManyAttachments    = errors.New("failed too many attachments in one message")
+	ErrLinkAttachments       = errors.New("failed link attachments to message")
+	ErrFileURLNotAllowed     = errors.New("failed file_url is no longer accepted, upload the file and send attachment_ids")
 
 	// Token
 	ErrGenerateAccessToken           = errors.New("failed to generate access token")
@@ -101,6 +126,30 @@ var (
 	// Redis
 	ErrPushToRedis = errors.New("failed push to redis")
 
+	// Rate limit
+	ErrRateLimited = errors.New("failed too many requests, please retry later")
+
+	// Websocket
+	ErrInvalidWSPayload      = errors.New("failed invalid websocket payload")
+	ErrNotSessionParticipant = errors.New("failed user is not a session participant")
+	ErrNotThesisParticipant  = errors.New("failed user is not a thesis participant")
+	ErrWSAuthUserMismatch    = errors.New("failed re-auth token belongs to another user")
+	ErrWSFrameThrottled      = errors.New("failed frame throttled, slow down")
+
+	// Event stream
+	ErrCreateStreamTicket  = errors.New("failed create stream ticket")
+	ErrStreamTicketInvalid = errors.New("failed stream ticket invalid or expired")
+
+	// Thesis
+	ErrLecturerCannotUpdateThesis      = errors.New("lecturer cannot update thesis")
+	ErrStudentCannotReadLecturerTheses = errors.New("student cannot read all lecturer thesis")
+
+	// Schedule
+	ErrStudentCannotApproveSchedule = errors.New("student cannot approve schedule")
+
+	// Presence
+	ErrGetPresence = errors.New("failed get presence")
+
 	// Parse
 	ErrParseStringToUUID = errors.New("failed parse string to uuid format")
 	ErrMarshalToJSON     = errors.New("failed marshal to JSON")
@@ -140,6 +189,32 @@ var (
 
 	// Message
 	ErrGetAllMessageWithPagination = errors.New("failed get all message with pagination")
+	ErrInvalidCursor               = errors.New("failed invalid cursor")
+	ErrSearchMessages              = errors.New("failed search messages")
+	ErrGetMessageByID              = errors.New("failed get message by id")
+	ErrUpdateMessage               = errors.New("failed update message")
+	ErrCreateMessageEdit           = errors.New("failed create message edit history")
+	ErrNotMessageSender            = errors.New("failed only sender can modify message")
+	ErrMessageAlreadyDeleted       = errors.New("failed message already deleted")
+	ErrMessageModifiedConcurrently = errors.New("failed message was modified by another request, please retry")
+	ErrSessionNotOngoing           = errors.New("failed session is not ongoing")
+	ErrUpsertReadCursor            = errors.New("failed upsert read cursor")
+	ErrNextMessageSeq              = errors.New("failed get next message seq")
+	ErrMessageSendInProgress       = errors.New("failed message with the same client message id is still being sent")
+	ErrParentMessageNotFound       = errors.New("failed parent message not found in this session")
+	ErrGetMessageReplies           = errors.New("failed get message replies")
+	ErrNotLecturerPinMessage       = errors.New("failed only supervisor lecturer can pin message")
+	ErrMessageAlreadyPinned        = errors.New("failed message already pinned")
+	ErrMessageNotPinned            = errors.New("failed message is not pinned")
+	ErrPinMessage                  = errors.New("failed pin message")
+	ErrUnpinMessage                = errors.New("failed unpin message")
+	ErrGetPinnedMessages           = errors.New("failed get pinned messages")
+
+	// Summary
+	ErrInvalidSummaryResult  = errors.New("failed invalid summary result payload")
+	ErrSummaryAlreadyApplied = errors.New("failed summary result already applied")
+	ErrCreateNote            = errors.New("failed create note")
+	ErrCreateOutbox          = errors.New("failed create outbox")
 )
 
 // Master
@@ -248,6 +323,8 @@ type (
 		Status    entity.SessionStatus `json:"status"`
 		Thesis    ThesisResponse       `json:"thesis"`
 		UserOwner UserResponse         `json:"user_owner"`
+
+		UnreadCount int64 `json:"unread_count"`
 	}
 	CustomSessionResponse struct {
 		ID        uuid.UUID            `json:"id"`
@@ -291,45 +368,376 @@ type (
 		Title     string    `json:"title"`
 		Message   string    `json:"message"`
 		IsRead    bool      `json:"is_read"`
+		Priority  string    `json:"priority"`
 		CreatedAt time.Time `json:"created_at"`
 	}
 )
 
+// Attachment
+type (
+	AttachmentResponse struct {
+		ID           uuid.UUID `json:"id"`
+		OriginalName string    `json:"original_name"`
+		URL          string    `json:"url"`
+		Size         int64     `json:"size"`
+		MimeType     string    `json:"mime_type"`
+		Checksum     string    `json:"checksum"`
+		UploaderID   uuid.UUID `json:"uploader_id"`
+		CreatedAt    string    `json:"created_at"`
+	}
+)
+
 // Message
 type (
 	MessageResponse struct {
-		ID              uuid.UUID          `json:"id"`
-		IsText          *bool              `json:"is_text"`
-		Text            string             `json:"text"`
-		FileURL         string             `json:"file_url,omitempty"`
-		Sender          CustomUserResponse `json:"sender"`
-		ParentMessageID *uuid.UUID         `json:"parent_message_id,omitempty"`
-		Timestamp       string             `json:"timestamp,omitempty"`
-	}
+		ID              uuid.UUID               `json:"id"`
+		Seq             int64                   `json:"seq"`
+		IsText          *bool                   `json:"is_text"`
+		Text            string                  `json:"text"`
+		FileURL         string                  `json:"file_url,omitempty"`
+		Sender          CustomUserResponse      `json:"sender"`
+		ParentMessageID *uuid.UUID              `json:"parent_message_id,omitempty"`
+		ClientMessageID string                  `json:"client_message_id,omitempty"`
+		Mentions        []entity.MessageMention `json:"mentions,omitempty"`
+		Attachments     []AttachmentResponse    `json:"attachments,omitempty"`
+		IsEdited        bool                    `json:"is_edited"`
+		EditedAt        string                  `json:"edited_at,omitempty"`
+		IsDeleted       bool                    `json:"is_deleted"`
+		Timestamp       string                  `json:"timestamp,omitempty"`
+		ReplyCount      int64                   `json:"reply_count"`
+		LastReply       *ThreadLastReply        `json:"last_reply,omitempty"`
+	}
+	// MessageEventPublish Seq naik satu per message di session, client memakai selisih seq untuk
+	// mendeteksi message yang terlewat lalu mengambilnya lewat cursor after
 	MessageEventPublish struct {
-		MessageID       uuid.UUID          `json:"id"`
-		Event           string             `json:"event"`
-		IsText          *bool              `json:"is_text"`
-		Text            string             `json:"text"`
-		FileURL         string             `json:"file_url,omitempty"`
-		Sender          CustomUserResponse `json:"sender"`
-		SessionID       uuid.UUID          `json:"session_id"`
-		ParentMessageID *uuid.UUID         `json:"parent_message_id,omitempty"`
-		Timestamp       string             `json:"timestamp,omitempty"`
+		MessageID       uuid.UUID               `json:"id"`
+		Seq             int64                   `json:"seq"`
+		Event           string                  `json:"event"`
+		IsText          *bool                   `json:"is_text"`
+		Text            string                  `json:"text"`
+		FileURL         string                  `json:"file_url,omitempty"`
+		Sender          CustomUserResponse      `json:"sender"`
+		SessionID       uuid.UUID               `json:"session_id"`
+		ParentMessageID *uuid.UUID              `json:"parent_message_id,omitempty"`
+		ClientMessageID string                  `json:"client_message_id,omitempty"`
+		Mentions        []entity.MessageMention `json:"mentions,omitempty"`
+		Attachments     []AttachmentResponse    `json:"attachments,omitempty"`
+		IsEdited        bool                    `json:"is_edited,omitempty"`
+		EditedAt        string                  `json:"edited_at,omitempty"`
+		IsDeleted       bool                    `json:"is_deleted,omitempty"`
+		Timestamp       string                  `json:"timestamp,omitempty"`
 	}
 	SendMessageRequest struct {
-		IsText          *bool      `json:"is_text" binding:"required"`
-		Text            string     `json:"text" binding:"required"`
-		FileURL         string     `json:"file_url,omitempty"`
-		ParentMessageID *uuid.UUID `json:"parent_message_id,omitempty"`
+		IsText          *bool       `json:"is_text" binding:"required"`
+		Text            string      `json:"text" binding:"required"`
+		FileURL         string      `json:"file_url,omitempty"` // tidak diterima lagi, pakai attachment_ids
+		ParentMessageID *uuid.UUID  `json:"parent_message_id,omitempty"`
+		AttachmentIDs   []uuid.UUID `json:"attachment_ids,omitempty"`
+		// ClientMessageID opsional, retry dengan nilai yang sama mengembalikan message yang sudah ada.
+		// lewat http bisa juga dikirim sebagai header Idempotency-Key
+		ClientMessageID string `json:"client_message_id,omitempty" binding:"max=128"`
+	}
+	MarkReadRequest struct {
+		MessageID uuid.UUID `json:"message_id" binding:"required"`
+	}
+	ReadCursorResponse struct {
+		SessionID         uuid.UUID `json:"session_id"`
+		UserID            uuid.UUID `json:"user_id"`
+		LastReadMessageID uuid.UUID `json:"last_read_message_id"`
+		LastReadSeq       int64     `json:"last_read_seq"`
+		LastReadAt        string    `json:"last_read_at"`
+	}
+	MessageReadEventPublish struct {
+		Event             string    `json:"event"`
+		SessionID         uuid.UUID `json:"session_id"`
+		UserID            uuid.UUID `json:"user_id"`
+		LastReadMessageID uuid.UUID `json:"last_read_message_id"`
+		LastReadSeq       int64     `json:"last_read_seq"`
+		LastReadAt        string    `json:"last_read_at"`
+	}
+	TypingEventPublish struct {
+		Event     string     `json:"event"`
+		SessionID uuid.UUID  `json:"session_id"`
+		UserID    uuid.UUID  `json:"user_id"`
+		Name      string     `json:"name,omitempty"`
+		IsTyping  bool       `json:"is_typing"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}
+	// MentionEventPublish dikirim langsung ke user yang di-mention, notifikasi-nya selalu disimpan
+	MentionEventPublish struct {
+		Event          string             `json:"event"`
+		Priority       string             `json:"priority"`
+		NotificationID uuid.UUID          `json:"notification_id"`
+		SessionID      uuid.UUID          `json:"session_id"`
+		ThesisID       uuid.UUID          `json:"thesis_id"`
+		MessageID      uuid.UUID          `json:"message_id"`
+		Sender         CustomUserResponse `json:"sender"`
+		Text           string             `json:"text"`
+		Timestamp      string             `json:"timestamp"`
+	}
+	EditMessageRequest struct {
+		Text    string `json:"text" binding:"required"`
+		FileURL string `json:"file_url,omitempty"` // tidak diterima lagi, attachment tidak bisa diubah lewat edit
+	}
+	// MessageCursorRequest keyset pagination, isi salah satu dari before / after (kosong = halaman terbaru)
+	MessageCursorRequest struct {
+		Before string `form:"before"`
+		After  string `form:"after"`
+		Limit  int    `form:"limit"`
+	}
+	// MessageCursor hasil decode cursor, seq dipakai sebagai batas eksklusif di redis & postgres
+	MessageCursor struct {
+		Seq int64
+	}
+	MessageCursorResponse struct {
+		Limit      int    `json:"limit"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		HasMore    bool   `json:"has_more"`
 	}
 	MessagePaginationResponse struct {
-		response.PaginationResponse
-		Data []MessageResponse `json:"data"`
+		Meta MessageCursorResponse `json:"meta"`
+		Data []MessageResponse     `json:"data"`
 	}
+	// MessagePaginationRepositoryResponse Messages selalu terbaru ke terlama
 	MessagePaginationRepositoryResponse struct {
-		response.PaginationResponse
 		Messages []entity.Message
+		HasMore  bool
+	}
+)
+
+// Thread
+type (
+	ThreadLastReply struct {
+		ID        uuid.UUID          `json:"id"`
+		Seq       int64              `json:"seq"`
+		Text      string             `json:"text"`
+		Sender    CustomUserResponse `json:"sender"`
+		Timestamp string             `json:"timestamp"`
+	}
+	// ThreadStats jumlah reply (tanpa yang sudah dihapus) dan reply terakhir satu parent message
+	ThreadStats struct {
+		ReplyCount int64
+		LastReply  *ThreadLastReply
+	}
+	// ThreadStatsRow hasil raw query reply terakhir per parent di postgres
+	ThreadStatsRow struct {
+		ParentMessageID  uuid.UUID
+		ReplyCount       int64
+		ID               uuid.UUID
+		Seq              int64
+		SenderID         uuid.UUID
+		SenderRole       string
+		SenderName       string
+		SenderIdentifier string
+		Text             string
+		CreatedAt        time.Time
+	}
+	ThreadReplyEventPublish struct {
+		Event           string           `json:"event"`
+		SessionID       uuid.UUID        `json:"session_id"`
+		ParentMessageID uuid.UUID        `json:"parent_message_id"`
+		Reply           MessageResponse  `json:"reply"`
+		ReplyCount      int64            `json:"reply_count"`
+		LastReply       *ThreadLastReply `json:"last_reply,omitempty"`
+	}
+)
+
+// Pin
+type (
+	PinnedMessageResponse struct {
+		Message  MessageResponse    `json:"message"`
+		PinnedBy CustomUserResponse `json:"pinned_by"`
+		PinnedAt string             `json:"pinned_at"`
+		// hanya diisi pada response unpin / event message_unpinned
+		UnpinnedBy *CustomUserResponse `json:"unpinned_by,omitempty"`
+		UnpinnedAt string              `json:"unpinned_at,omitempty"`
+	}
+	// MessagePinEventPublish event message_pinned / message_unpinned ke room session
+	MessagePinEventPublish struct {
+		Event     string                `json:"event"`
+		SessionID uuid.UUID             `json:"session_id"`
+		Pin       PinnedMessageResponse `json:"pin"`
+	}
+)
+
+// Websocket
+type (
+	// WSEnvelope format frame dua arah di /ws, id diisi client untuk korelasi ack / error
+	WSEnvelope struct {
+		Type    string          `json:"type"`
+		ID      string          `json:"id,omitempty"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+		Ack     bool            `json:"ack,omitempty"`
+	}
+	// WSRelayMessage dikirim lewat redis pub/sub ke instance yang memegang koneksi user / anggota room
+	WSRelayMessage struct {
+		Origin     string `json:"origin"`
+		UserID     string `json:"user_id,omitempty"`
+		Room       string `json:"room,omitempty"`
+		ExceptRoom string `json:"except_room,omitempty"` // koneksi user yang sudah ada di room ini dilewati
+		Data       []byte `json:"data"`
+	}
+	WSMetricsResponse struct {
+		ActiveConnections       int64 `json:"active_connections"`
+		ActiveUsers             int64 `json:"active_users"`
+		ConnectionsOpened       int64 `json:"connections_opened"`
+		ConnectionsClosed       int64 `json:"connections_closed"`
+		MessagesSent            int64 `json:"messages_sent"`
+		MessagesDropped         int64 `json:"messages_dropped"`
+		SlowConsumerDisconnects int64 `json:"slow_consumer_disconnects"`
+		PongTimeouts            int64 `json:"pong_timeouts"`
+		WriteErrors             int64 `json:"write_errors"`
+		FramesThrottled         int64 `json:"frames_throttled"`
+	}
+	WSErrorPayload struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	WSSendMessagePayload struct {
+		SessionID string `json:"session_id" binding:"required"`
+		SendMessageRequest
+	}
+	WSTypingPayload struct {
+		SessionID string `json:"session_id" binding:"required"`
+		IsTyping  bool   `json:"is_typing"`
+	}
+	WSMarkReadPayload struct {
+		SessionID string `json:"session_id" binding:"required"`
+		MarkReadRequest
+	}
+	WSSessionPayload struct {
+		SessionID string `json:"session_id" binding:"required"`
+	}
+	// WSSyncMessagesPayload ambil message setelah after_seq, dipakai client saat ada gap seq / reconnect
+	WSSyncMessagesPayload struct {
+		SessionID string `json:"session_id" binding:"required"`
+		AfterSeq  int64  `json:"after_seq" binding:"min=0"`
+		Limit     int    `json:"limit"`
+	}
+	WSAuthPayload struct {
+		Token string `json:"token" binding:"required"`
+	}
+	// WSEventLogEntry event yang disimpan di log per user untuk replay setelah reconnect
+	WSEventLogEntry struct {
+		ID   int64           `json:"id"`
+		Data json.RawMessage `json:"data"`
+	}
+	WSResumePayload struct {
+		LastEventID int64 `json:"last_event_id"`
+	}
+	WSResumeResponse struct {
+		Replayed    int   `json:"replayed"`
+		LastEventID int64 `json:"last_event_id"`
+	}
+	StreamTicketResponse struct {
+		Ticket    string    `json:"ticket"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+)
+
+// Presence
+type (
+	PresenceResponse struct {
+		UserID     uuid.UUID   `json:"user_id"`
+		Name       string      `json:"name"`
+		Identifier string      `json:"identifier"`
+		Role       entity.Role `json:"role"`
+		IsOnline   bool        `json:"is_online"`
+		LastSeen   *time.Time  `json:"last_seen,omitempty"`
+	}
+	PresenceEventPublish struct {
+		Event    string           `json:"event"`
+		Presence PresenceResponse `json:"presence"`
+	}
+)
+
+// Rate Limit
+type (
+	// RateLimitRule token bucket: Capacity jumlah burst yang diizinkan, RefillPerSecond token yang
+	// kembali setiap detik (rata-rata request per detik dalam jangka panjang)
+	RateLimitRule struct {
+		Capacity        int
+		RefillPerSecond float64
+	}
+	RateLimitResult struct {
+		Allowed    bool
+		Limit      int
+		Remaining  int
+		RetryAfter time.Duration // kapan satu token tersedia lagi, 0 kalau allowed
+		ResetAfter time.Duration // kapan bucket penuh lagi
+	}
+	// RateLimitError dikembalikan service saat bucket habis, errors.Is(err, ErrRateLimited) bernilai true
+	RateLimitError struct {
+		Result RateLimitResult
+	}
+)
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Headers header standar rate limit, Retry-After hanya diisi saat request ditolak
+func (r *RateLimitResult) Headers() map[string]string {
+	headers := map[string]string{
+		"X-RateLimit-Limit":     strconv.Itoa(r.Limit),
+		"X-RateLimit-Remaining": strconv.Itoa(r.Remaining),
+		"X-RateLimit-Reset":     strconv.FormatInt(ceilSeconds(r.ResetAfter), 10),
+	}
+	if !r.Allowed {
+		headers["Retry-After"] = strconv.FormatInt(ceilSeconds(r.RetryAfter), 10)
+	}
+
+	return headers
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// Search
+type (
+	MessageSearchRequest struct {
+		Query string `form:"q" binding:"required"`
+		response.PaginationRequest
+	}
+	// MessageSearchRow hasil raw query full-text search postgres
+	MessageSearchRow struct {
+		MessageID        uuid.UUID
+		Seq              int64
+		SessionID        uuid.UUID
+		ThesisID         uuid.UUID
+		SenderID         uuid.UUID
+		SenderRole       string
+		SenderName       string
+		SenderIdentifier string
+		Text             string
+		Highlight        string
+		CreatedAt        time.Time
+		Rank             float64
+	}
+	MessageSearchContext struct {
+		Before []MessageResponse `json:"before"`
+		After  []MessageResponse `json:"after"`
+	}
+	MessageSearchResponse struct {
+		MessageID uuid.UUID            `json:"message_id"`
+		Seq       int64                `json:"seq"`
+		SessionID uuid.UUID            `json:"session_id"`
+		ThesisID  uuid.UUID            `json:"thesis_id"`
+		Sender    CustomUserResponse   `json:"sender"`
+		Text      string               `json:"text"`
+		Highlight string               `json:"highlight"`
+		IsLive    bool                 `json:"is_live"`
+		Timestamp string               `json:"timestamp"`
+		Context   MessageSearchContext `json:"context"`
+	}
+	MessageSearchPaginationResponse struct {
+		response.PaginationResponse
+		Data []MessageSearchResponse `json:"data"`
 	}
 )
 
@@ -349,6 +757,8 @@ type (
 		ThesisInfo ThesisSummary `json:"thesis_info"`
 
 		Messages []MessageSummary `json:"messages"`
+		// Pinned keputusan penting yang di-pin dosen, urut dari yang paling awal di-pin
+		Pinned []PinnedMessageSummary `json:"pinned"`
 	}
 
 	MessageSummary struct {
@@ -360,6 +770,25 @@ type (
 		ParentMessageID *uuid.UUID         `json:"parent_message_id,omitempty"`
 		Timestamp       string             `json:"timestamp"`
 	}
+	PinnedMessageSummary struct {
+		MessageSummary
+		PinnedBy CustomUserResponse `json:"pinned_by"`
+		PinnedAt string             `json:"pinned_at"`
+	}
+)
+
+// Summary Result Message
+type (
+	TaskSummaryResult struct {
+		SessionID uuid.UUID `json:"session_id"`
+		Summary   string    `json:"summary"`
+	}
+	SummaryEventPublish struct {
+		Event     string    `json:"event"`
+		SessionID uuid.UUID `json:"session_id"`
+		ThesisID  uuid.UUID `json:"thesis_id"`
+		NoteID    uuid.UUID `json:"note_id"`
+	}
 )
 
 // Note / Summary
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+// Attachment file hasil upload. message_id diisi saat file dipakai di message, tanpa foreign key
+// karena message bisa saja masih hanya ada di redis
+type Attachment struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	OriginalName string    `gorm:"not null" json:"original_name"`
+	Path         string    `gorm:"not null" json:"path"`
+	Size         int64     `gorm:"not null" json:"size"`
+	MimeType     string    `gorm:"not null" json:"mime_type"`
+	Checksum     string    `gorm:"not null;index" json:"checksum"` // sha256 hex
+
+	UploaderID uuid.UUID `gorm:"type:uuid;index" json:"uploader_id"`
+	Uploader   User      `gorm:"foreignKey:UploaderID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"uploader"`
+
+	MessageID *uuid.UUID `gorm:"type:uuid;index" json:"message_id,omitempty"`
+	SessionID *uuid.UUID `gorm:"type:uuid;index" json:"session_id,omitempty"`
+
+	TimeStamp
+}
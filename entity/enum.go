@@ -8,6 +8,10 @@ type (
 	Progress       string
 	SessionStatus  string
 	ScheduleStatus string
+	OutboxStatus   string
+	MessageAction  string
+
+	NotificationPriority string
 )
 
 const (
@@ -36,6 +40,15 @@ const (
 	ONGOING            SessionStatus = constants.ENUM_SESSION_STATUS_ONGOING
 	PROCESSING_SUMMARY SessionStatus = constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY
 	FINISHED           SessionStatus = constants.ENUM_SESSION_STATUS_FINSIHED
+
+	OUTBOX_PENDING OutboxStatus = constants.ENUM_OUTBOX_STATUS_PENDING
+	OUTBOX_SENT    OutboxStatus = constants.ENUM_OUTBOX_STATUS_SENT
+
+	MESSAGE_EDIT   MessageAction = constants.ENUM_MESSAGE_ACTION_EDIT
+	MESSAGE_DELETE MessageAction = constants.ENUM_MESSAGE_ACTION_DELETE
+
+	NOTIFICATION_NORMAL NotificationPriority = constants.ENUM_NOTIFICATION_PRIORITY_NORMAL
+	NOTIFICATION_HIGH   NotificationPriority = constants.ENUM_NOTIFICATION_PRIORITY_HIGH
 )
 
 func IsValidRole(r Role) bool {
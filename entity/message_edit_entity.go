@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+// MessageEdit menyimpan isi message sebelum diedit / dihapus.
+// tanpa foreign key karena message bisa saja masih hanya ada di redis
+type MessageEdit struct {
+	ID     uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	Action MessageAction `gorm:"not null" json:"action"`
+
+	PreviousText    string `json:"previous_text"`
+	PreviousFileURL string `json:"previous_file_url,omitempty"`
+
+	MessageID uuid.UUID `gorm:"type:uuid;index" json:"message_id"`
+	SessionID uuid.UUID `gorm:"type:uuid;index" json:"session_id"`
+	EditorID  uuid.UUID `gorm:"type:uuid;index" json:"editor_id"`
+
+	TimeStamp
+}
@@ -1,12 +1,15 @@
 package entity
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Message struct {
 	ID      uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Seq     int64     `gorm:"not null;default:0" json:"seq"` // urutan per session dari INCR redis, unique index dibuat di migrasi
 	IsText  bool      `gorm:"not null" json:"is_text"`
 	Text    string    `json:"text"`
 	FileURL string    `json:"file_url,omitempty"`
@@ -20,9 +23,26 @@ type Message struct {
 
 	ParentMessageID *uuid.UUID `gorm:"type:uuid;index" json:"parent_message_id,omitempty"`
 
+	// ClientMessageID id / Idempotency-Key dari client, id message diturunkan dari nilai ini
+	ClientMessageID string `gorm:"index" json:"client_message_id,omitempty"`
+
+	// Mentions hasil resolve @nip / @nim saat message dikirim, disimpan apa adanya sebagai jsonb
+	Mentions []MessageMention `gorm:"type:jsonb;serializer:json" json:"mentions,omitempty"`
+
+	IsEdited  bool       `gorm:"not null;default:false" json:"is_edited"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	IsDeleted bool       `gorm:"not null;default:false" json:"is_deleted"`
+
 	TimeStamp
 }
 
+type MessageMention struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Identifier string    `json:"identifier"`
+	Name       string    `json:"name"`
+	Role       Role      `json:"role"`
+}
+
 func (m *Message) BeforeCreate(tx *gorm.DB) error {
 	var err error
 
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+// MessagePin message penting yang di-pin dosen pembimbing pada sebuah session.
+// tanpa foreign key ke message karena message bisa saja masih hanya ada di redis
+type MessagePin struct {
+	ID uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+
+	SessionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_message_pin_session_message" json:"session_id"`
+	Session   Session   `gorm:"foreignKey:SessionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"session,omitempty"`
+
+	MessageID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_message_pin_session_message" json:"message_id"`
+
+	PinnedByID uuid.UUID `gorm:"type:uuid;index" json:"pinned_by_id"`
+	PinnedBy   User      `gorm:"foreignKey:PinnedByID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"pinned_by,omitempty"`
+
+	TimeStamp
+}
@@ -10,6 +10,8 @@ type Notification struct {
 	Message string    `gorm:"not null" json:"message"`
 	IsRead  bool      `json:"is_read"`
 
+	Priority NotificationPriority `gorm:"not null;default:normal" json:"priority"`
+
 	UserID uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
 	User   User      `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user"`
 
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Outbox struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	Queue         string       `gorm:"not null" json:"queue"`
+	Payload       string       `gorm:"type:jsonb;not null" json:"payload"`
+	Status        OutboxStatus `gorm:"default:pending;index" json:"status"`
+	Attempts      int          `gorm:"not null;default:0" json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	NextAttemptAt time.Time    `gorm:"index" json:"next_attempt_at"`
+	SentAt        *time.Time   `json:"sent_at,omitempty"`
+
+	// id data asal event, contoh: session id untuk summary task
+	AggregateID uuid.UUID `gorm:"type:uuid;index" json:"aggregate_id"`
+
+	TimeStamp
+}
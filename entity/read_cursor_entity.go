@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadCursor posisi terakhir yang sudah dibaca user pada sebuah session
+type ReadCursor struct {
+	ID uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+
+	UserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_read_cursor_user_session" json:"user_id"`
+	User   User      `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
+
+	SessionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_read_cursor_user_session" json:"session_id"`
+	Session   Session   `gorm:"foreignKey:SessionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"session,omitempty"`
+
+	// tanpa foreign key karena message bisa saja masih hanya ada di redis
+	LastReadMessageID uuid.UUID `gorm:"type:uuid" json:"last_read_message_id"`
+	// seq jadi acuan maju / mundur cursor dan hitung unread, LastReadAt hanya untuk ditampilkan
+	LastReadSeq int64     `gorm:"not null;default:0" json:"last_read_seq"`
+	LastReadAt  time.Time `gorm:"not null" json:"last_read_at"`
+
+	TimeStamp
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	IEventHandler interface {
+		CreateTicket(ctx *gin.Context)
+		Stream(ctx *gin.Context)
+	}
+
+	eventHandler struct {
+		wsService           service.IWebsocketService
+		participantService  service.IParticipantService
+		streamTicketService service.IStreamTicketService
+	}
+)
+
+func NewEventHandler(wsService service.IWebsocketService, participantService service.IParticipantService, streamTicketService service.IStreamTicketService) *eventHandler {
+	return &eventHandler{
+		wsService:           wsService,
+		participantService:  participantService,
+		streamTicketService: streamTicketService,
+	}
+}
+
+// CreateTicket ticket sekali pakai untuk membuka stream dari EventSource (?ticket=)
+func (eh *eventHandler) CreateTicket(ctx *gin.Context) {
+	result, err := eh.streamTicketService.IssueTicket(ctx)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(fmt.Sprintf("%s stream ticket", dto.FAILED_CREATE), err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(fmt.Sprintf("%s stream ticket", dto.SUCCESS_CREATE), result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Stream membuka koneksi sse, ?session_id= (boleh lebih dari satu) untuk ikut room session
+func (eh *eventHandler) Stream(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+
+	var rooms []string
+	for _, sessionID := range ctx.QueryArray("session_id") {
+		ok, err := eh.participantService.IsSessionParticipant(ctx, sessionID, userID)
+		if err == nil && !ok {
+			err = dto.ErrNotSessionParticipant
+		}
+		if err != nil {
+			status := mapErrorToStatus(err)
+			res := response.BuildResponseFailed(fmt.Sprintf("%s event stream", dto.FAILED_GET_DETAIL), err.Error(), nil)
+			ctx.AbortWithStatusJSON(status, res)
+			return
+		}
+		rooms = append(rooms, service.SessionRoom(sessionID))
+	}
+
+	eh.wsService.HandleEventStream(ctx, rooms)
+}
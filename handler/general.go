@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Amierza/chat-service/dto"
+	"github.com/gin-gonic/gin"
 )
 
 func mapErrorToStatus(err error) int {
+	if errors.Is(err, dto.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+
 	switch err {
 	case
 		// invalid input
@@ -16,13 +22,48 @@ func mapErrorToStatus(err error) int {
 		dto.ErrGetActiveSessionBySessionID,
 		dto.ErrSessionAlreadyStarted,
 		dto.ErrUnableStartAndJoinSessionWithTheSameUser,
-		dto.ErrIncorrectPassword:
+		dto.ErrIncorrectPassword,
+		dto.ErrSessionNotOngoing,
+		dto.ErrMessageAlreadyDeleted,
+		dto.ErrInvalidWSPayload,
+		dto.ErrInvalidCursor,
+		dto.ErrParentMessageNotFound,
+		dto.ErrAttachmentAlreadyUsed,
+		dto.ErrTooManyAttachments,
+		dto.ErrFileURLNotAllowed:
 		return http.StatusBadRequest
-	case dto.ErrNotFound:
+	case dto.ErrNotFound,
+		dto.ErrAttachmentNotFound,
+		dto.ErrMessageNotPinned:
 		return http.StatusNotFound
-	case dto.ErrUnauthorized:
+	case dto.ErrMessageSendInProgress,
+		dto.ErrMessageModifiedConcurrently,
+		dto.ErrMessageAlreadyPinned:
+		return http.StatusConflict
+	case dto.ErrUnauthorized,
+		dto.ErrStreamTicketInvalid:
 		return http.StatusUnauthorized
+	case dto.ErrNotMessageSender,
+		dto.ErrNotSessionParticipant,
+		dto.ErrNotThesisParticipant,
+		dto.ErrLecturerCannotUpdateThesis,
+		dto.ErrStudentCannotReadLecturerTheses,
+		dto.ErrStudentCannotApproveSchedule,
+		dto.ErrNotAttachmentOwner,
+		dto.ErrNotLecturerPinMessage:
+		return http.StatusForbidden
 	default:
 		return http.StatusInternalServerError
 	}
 }
+
+// setRateLimitHeaders header 429 untuk penolakan dari rate limit level service
+func setRateLimitHeaders(ctx *gin.Context, err error) {
+	var rateLimitErr *dto.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return
+	}
+	for key, value := range rateLimitErr.Result.Headers() {
+		ctx.Header(key, value)
+	}
+}
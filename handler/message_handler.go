@@ -14,6 +14,13 @@ type (
 	IMessageHandler interface {
 		Send(ctx *gin.Context)
 		List(ctx *gin.Context)
+		Replies(ctx *gin.Context)
+		Edit(ctx *gin.Context)
+		Delete(ctx *gin.Context)
+		MarkRead(ctx *gin.Context)
+		Pin(ctx *gin.Context)
+		Unpin(ctx *gin.Context)
+		Pinned(ctx *gin.Context)
 	}
 
 	messageHandler struct {
@@ -34,22 +41,31 @@ func (mh *messageHandler) Send(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
 		return
 	}
+	if payload.ClientMessageID == "" {
+		payload.ClientMessageID = ctx.GetHeader("Idempotency-Key")
+	}
+	if len(payload.ClientMessageID) > 128 {
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_SEND_MESSAGE, "Idempotency-Key must be at most 128 characters", nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
 
 	sessionID := ctx.Param("session_id")
-	err := mh.messageService.Send(ctx, payload, sessionID)
+	result, err := mh.messageService.Send(ctx, payload, sessionID)
 	if err != nil {
+		setRateLimitHeaders(ctx, err)
 		status := mapErrorToStatus(err)
 		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_SEND_MESSAGE, err.Error(), nil)
 		ctx.AbortWithStatusJSON(status, res)
 		return
 	}
 
-	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_SEND_MESSAGE, nil)
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_SEND_MESSAGE, result)
 	ctx.JSON(http.StatusCreated, res)
 }
 
 func (mh *messageHandler) List(ctx *gin.Context) {
-	var payload response.PaginationRequest
+	var payload dto.MessageCursorRequest
 	if err := ctx.ShouldBind(&payload); err != nil {
 		res := response.BuildResponseFailed(fmt.Sprintf("%s messages", dto.FAILED_GET_ALL), err.Error(), nil)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
@@ -59,8 +75,9 @@ func (mh *messageHandler) List(ctx *gin.Context) {
 	sessionID := ctx.Param("session_id")
 	result, err := mh.messageService.List(ctx, payload, sessionID)
 	if err != nil {
+		status := mapErrorToStatus(err)
 		res := response.BuildResponseFailed(fmt.Sprintf("%s messages", dto.FAILED_GET_ALL), err.Error(), nil)
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, res)
+		ctx.AbortWithStatusJSON(status, res)
 		return
 	}
 
@@ -68,8 +85,138 @@ func (mh *messageHandler) List(ctx *gin.Context) {
 		Status:   true,
 		Messsage: fmt.Sprintf("%s messages", dto.SUCCESS_GET_ALL),
 		Data:     result.Data,
-		Meta:     result.PaginationResponse,
+		Meta:     result.Meta,
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Replies(ctx *gin.Context) {
+	var payload dto.MessageCursorRequest
+	if err := ctx.ShouldBind(&payload); err != nil {
+		res := response.BuildResponseFailed(fmt.Sprintf("%s replies", dto.FAILED_GET_ALL), err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sessionID := ctx.Param("session_id")
+	messageID := ctx.Param("id")
+	result, err := mh.messageService.Replies(ctx, payload, sessionID, messageID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(fmt.Sprintf("%s replies", dto.FAILED_GET_ALL), err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.Response{
+		Status:   true,
+		Messsage: fmt.Sprintf("%s replies", dto.SUCCESS_GET_ALL),
+		Data:     result.Data,
+		Meta:     result.Meta,
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Edit(ctx *gin.Context) {
+	var payload dto.EditMessageRequest
+	if err := ctx.ShouldBind(&payload); err != nil {
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_EDIT_MESSAGE, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sessionID := ctx.Param("session_id")
+	messageID := ctx.Param("id")
+	result, err := mh.messageService.Edit(ctx, payload, sessionID, messageID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_EDIT_MESSAGE, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_EDIT_MESSAGE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Delete(ctx *gin.Context) {
+	sessionID := ctx.Param("session_id")
+	messageID := ctx.Param("id")
+	result, err := mh.messageService.Delete(ctx, sessionID, messageID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_MESSAGE, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_MESSAGE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) MarkRead(ctx *gin.Context) {
+	var payload dto.MarkReadRequest
+	if err := ctx.ShouldBind(&payload); err != nil {
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_MARK_READ, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sessionID := ctx.Param("session_id")
+	result, err := mh.messageService.MarkRead(ctx, payload, sessionID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_MARK_READ, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_MARK_READ, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Pin(ctx *gin.Context) {
+	sessionID := ctx.Param("session_id")
+	messageID := ctx.Param("id")
+	result, err := mh.messageService.Pin(ctx, sessionID, messageID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_PIN_MESSAGE, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_PIN_MESSAGE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Unpin(ctx *gin.Context) {
+	sessionID := ctx.Param("session_id")
+	messageID := ctx.Param("id")
+	result, err := mh.messageService.Unpin(ctx, sessionID, messageID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_UNPIN_MESSAGE, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UNPIN_MESSAGE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (mh *messageHandler) Pinned(ctx *gin.Context) {
+	sessionID := ctx.Param("session_id")
+	result, err := mh.messageService.Pinned(ctx, sessionID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_GET_PINNED_MESSAGES, err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
 	}
 
+	res := response.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_PINNED_MESSAGES, result)
 	ctx.JSON(http.StatusOK, res)
 }
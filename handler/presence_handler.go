@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	IPresenceHandler interface {
+		GetThesisPresence(ctx *gin.Context)
+	}
+
+	presenceHandler struct {
+		presenceService service.IPresenceService
+	}
+)
+
+func NewPresenceHandler(presenceService service.IPresenceService) *presenceHandler {
+	return &presenceHandler{
+		presenceService: presenceService,
+	}
+}
+
+func (ph *presenceHandler) GetThesisPresence(ctx *gin.Context) {
+	thesisID := ctx.Param("id")
+	result, err := ph.presenceService.GetThesisPresence(ctx, thesisID)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(fmt.Sprintf("%s presence", dto.FAILED_GET_ALL), err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.BuildResponseSuccess(fmt.Sprintf("%s presence", dto.SUCCESS_GET_ALL), result)
+	ctx.JSON(http.StatusOK, res)
+}
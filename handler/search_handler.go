@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ISearchHandler interface {
+		SearchMessages(ctx *gin.Context)
+	}
+
+	searchHandler struct {
+		searchService service.ISearchService
+	}
+)
+
+func NewSearchHandler(searchService service.ISearchService) *searchHandler {
+	return &searchHandler{
+		searchService: searchService,
+	}
+}
+
+func (sh *searchHandler) SearchMessages(ctx *gin.Context) {
+	var payload dto.MessageSearchRequest
+	if err := ctx.ShouldBind(&payload); err != nil {
+		res := response.BuildResponseFailed(fmt.Sprintf("%s messages", dto.FAILED_GET_ALL), err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := sh.searchService.SearchMessages(ctx, payload)
+	if err != nil {
+		status := mapErrorToStatus(err)
+		res := response.BuildResponseFailed(fmt.Sprintf("%s messages", dto.FAILED_GET_ALL), err.Error(), nil)
+		ctx.AbortWithStatusJSON(status, res)
+		return
+	}
+
+	res := response.Response{
+		Status:   true,
+		Messsage: fmt.Sprintf("%s messages", dto.SUCCESS_GET_ALL),
+		Data:     result.Data,
+		Meta:     result.PaginationResponse,
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const wsTypingInterval = time.Second
+
+type (
+	IWebsocketHandler interface {
+		RegisterCommands()
+		Metrics(ctx *gin.Context)
+		SendMessage(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		Typing(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		TypingStarted(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		TypingStopped(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		MarkRead(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		JoinSession(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		LeaveSession(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		Subscribe(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		Unsubscribe(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		Resume(ctx context.Context, payload json.RawMessage) (interface{}, error)
+		SyncMessages(ctx context.Context, payload json.RawMessage) (interface{}, error)
+	}
+
+	websocketHandler struct {
+		wsService          service.IWebsocketService
+		messageService     service.IMessageService
+		sessionService     service.ISessionService
+		participantService service.IParticipantService
+	}
+)
+
+func NewWebsocketHandler(wsService service.IWebsocketService, messageService service.IMessageService, sessionService service.ISessionService, participantService service.IParticipantService) *websocketHandler {
+	return &websocketHandler{
+		wsService:          wsService,
+		messageService:     messageService,
+		sessionService:     sessionService,
+		participantService: participantService,
+	}
+}
+
+// RegisterCommands mendaftarkan semua command frame /ws ke websocket service
+func (wh *websocketHandler) RegisterCommands() {
+	wh.wsService.RegisterHandler("send_message", wh.SendMessage)
+	wh.wsService.RegisterHandler("typing", wh.Typing)
+	wh.wsService.RegisterHandler("typing_started", wh.TypingStarted)
+	wh.wsService.RegisterHandler("typing_stopped", wh.TypingStopped)
+	wh.wsService.RegisterHandler("mark_read", wh.MarkRead)
+	wh.wsService.RegisterHandler("join_session", wh.JoinSession)
+	wh.wsService.RegisterHandler("leave_session", wh.LeaveSession)
+	wh.wsService.RegisterHandler("subscribe", wh.Subscribe)
+	wh.wsService.RegisterHandler("unsubscribe", wh.Unsubscribe)
+	wh.wsService.RegisterHandler("resume", wh.Resume)
+	wh.wsService.RegisterHandler("sync_messages", wh.SyncMessages)
+
+	// typing dikirim client setiap ketikan, dibatasi per koneksi supaya tidak membanjiri room
+	wh.wsService.Throttle("typing", wsTypingInterval)
+	wh.wsService.Throttle("typing_started", wsTypingInterval)
+	wh.wsService.Throttle("typing_stopped", wsTypingInterval)
+}
+
+func (wh *websocketHandler) SendMessage(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSendMessagePayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return wh.messageService.Send(ctx, req.SendMessageRequest, req.SessionID)
+}
+
+func (wh *websocketHandler) Typing(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSTypingPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return nil, wh.messageService.Typing(ctx, req.SessionID, req.IsTyping)
+}
+
+func (wh *websocketHandler) TypingStarted(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return nil, wh.messageService.Typing(ctx, req.SessionID, true)
+}
+
+func (wh *websocketHandler) TypingStopped(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return nil, wh.messageService.Typing(ctx, req.SessionID, false)
+}
+
+func (wh *websocketHandler) MarkRead(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSMarkReadPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return wh.messageService.MarkRead(ctx, req.MarkReadRequest, req.SessionID)
+}
+
+func (wh *websocketHandler) JoinSession(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	res, err := wh.sessionService.Join(ctx, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// yang join lewat websocket langsung ikut room session
+	if err := wh.participantService.SubscribeSession(ctx, req.SessionID); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (wh *websocketHandler) LeaveSession(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return wh.sessionService.Leave(ctx, req.SessionID)
+}
+
+func (wh *websocketHandler) Subscribe(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return nil, wh.participantService.SubscribeSession(ctx, req.SessionID)
+}
+
+func (wh *websocketHandler) Unsubscribe(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSessionPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return nil, wh.participantService.UnsubscribeSession(ctx, req.SessionID)
+}
+
+// Resume replay event yang terlewat setelah last_event_id milik client
+func (wh *websocketHandler) Resume(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSResumePayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return wh.wsService.Replay(ctx, req.LastEventID)
+}
+
+// SyncMessages message session setelah after_seq (terlama ke terbaru per halaman sesuai List mode after)
+func (wh *websocketHandler) SyncMessages(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var req dto.WSSyncMessagesPayload
+	if err := bindPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	return wh.messageService.List(ctx, dto.MessageCursorRequest{
+		After: strconv.FormatInt(req.AfterSeq, 10),
+		Limit: req.Limit,
+	}, req.SessionID)
+}
+
+func (wh *websocketHandler) Metrics(ctx *gin.Context) {
+	res := response.BuildResponseSuccess(fmt.Sprintf("%s websocket metrics", dto.SUCCESS_GET_DETAIL), wh.wsService.Metrics())
+	ctx.JSON(http.StatusOK, res)
+}
+
+// bindPayload decode payload frame lalu validasi tag binding sama seperti request REST
+func bindPayload(payload json.RawMessage, obj interface{}) error {
+	if len(payload) == 0 {
+		return dto.ErrInvalidWSPayload
+	}
+	if err := json.Unmarshal(payload, obj); err != nil {
+		return dto.ErrInvalidWSPayload
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"errors"
+	"strconv"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor cursor message adalah seq message itu sendiri, jadi client yang mendeteksi gap
+// bisa langsung membentuk cursor dari seq terakhir yang diterima
+func EncodeCursor(seq int64) string {
+	return strconv.FormatInt(seq, 10)
+}
+
+// DecodeCursor 0 berarti sebelum message pertama (dipakai untuk after)
+func DecodeCursor(cursor string) (int64, error) {
+	seq, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return seq, nil
+}
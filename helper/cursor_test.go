@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, seq := range []int64{0, 1, 42, 1 << 40} {
+		got, err := DecodeCursor(EncodeCursor(seq))
+		if err != nil {
+			t.Fatalf("DecodeCursor(EncodeCursor(%d)) error: %v", seq, err)
+		}
+		if got != seq {
+			t.Errorf("round trip %d: got %d", seq, got)
+		}
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    int64
+		wantErr bool
+	}{
+		{name: "zero", cursor: "0", want: 0},
+		{name: "positive", cursor: "15", want: 15},
+		{name: "empty", cursor: "", wantErr: true},
+		{name: "negative", cursor: "-1", wantErr: true},
+		{name: "not a number", cursor: "abc", wantErr: true},
+		{name: "legacy base64 cursor", cursor: "eyJzY29yZSI6MX0=", wantErr: true},
+		{name: "fraction", cursor: "1.5", wantErr: true},
+		{name: "whitespace", cursor: " 1", wantErr: true},
+		{name: "overflow", cursor: "9223372036854775808", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeCursor(%q) unexpected error: %v", tt.cursor, err)
+			}
+			if got != tt.want {
+				t.Errorf("DecodeCursor(%q) = %d, want %d", tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
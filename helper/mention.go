@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// @ harus di awal teks atau setelah karakter non-word supaya alamat email tidak ikut terbaca
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9]+)`)
+
+// ParseMentions mengambil identifier (nip / nim) dari @mention, unik dan urut kemunculan
+func ParseMentions(text string) []string {
+	var identifiers []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		identifier := strings.ToLower(match[1])
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		identifiers = append(identifiers, match[1])
+	}
+
+	return identifiers
+}
@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "no mention", text: "halo semua", want: nil},
+		{name: "start of text", text: "@5025211001 tolong dicek", want: []string{"5025211001"}},
+		{name: "after space", text: "pak @198501012010 sudah saya revisi", want: []string{"198501012010"}},
+		{name: "after punctuation", text: "(@abc) dan,@def", want: []string{"abc", "def"}},
+		{name: "trailing punctuation", text: "cek @abc123.", want: []string{"abc123"}},
+		{name: "email is ignored", text: "kirim ke budi@example.com", want: nil},
+		{name: "double at is ignored", text: "@@abc", want: nil},
+		{name: "lone at", text: "@ saja", want: nil},
+		{name: "duplicates keep first", text: "@abc @def @abc", want: []string{"abc", "def"}},
+		{name: "case insensitive duplicates keep first spelling", text: "@ABC @abc", want: []string{"ABC"}},
+		{name: "stops at non alphanumeric", text: "@abc_def", want: []string{"abc"}},
+		{name: "newline", text: "halo\n@abc", want: []string{"abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseMentions(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type (
 		ValidateToken(token string) (*jwt.Token, error)
 		GetUserIDByToken(tokenString string) (string, error)
 		GetUserRoleByToken(tokenString string) (string, error)
+		GetExpiryByToken(tokenString string) (time.Time, error)
 	}
 
 	jwtCustomClaim struct {
@@ -129,3 +130,17 @@ func (j *JWT) GetUserRoleByToken(tokenString string) (string, error) {
 
 	return role, nil
 }
+
+func (j *JWT) GetExpiryByToken(tokenString string) (time.Time, error) {
+	token, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return time.Time{}, dto.ErrValidateToken
+	}
+
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil || !token.Valid {
+		return time.Time{}, dto.ErrTokenInvalid
+	}
+
+	return exp.Time, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -54,56 +55,105 @@ func main() {
 		authHandler = handler.NewAuthHandler(authService)
 
 		// Websocket
-		wsService = service.NewWebSocketService(jwt, redisClient)
+		eventLogRepo = repository.NewEventLogRepository(redisClient)
+		wsService    = service.NewWebSocketService(jwt, redisClient, eventLogRepo)
+
+		// Rate limit
+		rateLimitRepo    = repository.NewRateLimitRepository(redisClient)
+		rateLimitService = service.NewRateLimitService(rateLimitRepo, zapLogger)
 
 		// Files
-		fileService = service.NewFileService()
-		fileHandler = handler.NewFileHandler(fileService)
+		attachmentRepo = repository.NewAttachmentRepository(db)
+		fileService    = service.NewFileService(attachmentRepo, zapLogger, jwt)
+		fileHandler    = handler.NewFileHandler(fileService)
 
 		// User
 		userRepo    = repository.NewUserRepository(db)
 		userService = service.NewUserService(userRepo, zapLogger, jwt)
 		userHandler = handler.NewUserHandler(userService)
 
+		// Participant
+		sessionRepo        = repository.NewSessionRepository(db)
+		participantRepo    = repository.NewParticipantRepository(redisClient)
+		participantService = service.NewParticipantService(participantRepo, sessionRepo, userRepo, zapLogger, wsService, jwt)
+
 		// Thesis
 		thesisRepo    = repository.NewThesisRepository(db)
-		thesisService = service.NewThesisService(thesisRepo, userRepo, zapLogger, jwt)
+		thesisService = service.NewThesisService(thesisRepo, userRepo, zapLogger, jwt, participantService)
 		thesisHandler = handler.NewThesisHandler(thesisService)
 
+		// Presence
+		presenceRepo    = repository.NewPresenceRepository(redisClient)
+		presenceService = service.NewPresenceService(presenceRepo, thesisRepo, userRepo, zapLogger, wsService, jwt, participantService)
+		presenceHandler = handler.NewPresenceHandler(presenceService)
+
 		// Notification
 		notificationRepo    = repository.NewNotificationRepository(db)
 		notificationService = service.NewNotificationService(notificationRepo, zapLogger, jwt)
 		notificationHandler = handler.NewNotificationHandler(notificationService)
 
 		// Session
-		sessionRepo    = repository.NewSessionRepository(db)
-		messageRepo    = repository.NewMessageRepository(db, zapLogger, redisClient)
-		sessionService = service.NewSessionService(sessionRepo, messageRepo, notificationRepo, userRepo, zapLogger, rabbitConn, wsService, jwt, redisClient)
-		sessionHandler = handler.NewSessionHandler(sessionService)
+		messageRepo      = repository.NewMessageRepository(db, zapLogger, redisClient)
+		outboxRepo       = repository.NewOutboxRepository(db)
+		outboxRelay      = service.NewOutboxRelayService(outboxRepo, zapLogger, rabbitConn)
+		messagePersister = service.NewMessagePersisterService(messageRepo, sessionRepo, zapLogger)
+		sessionService   = service.NewSessionService(sessionRepo, messageRepo, notificationRepo, userRepo, zapLogger, outboxRepo, wsService, messagePersister, jwt, redisClient, participantService)
+		sessionHandler   = handler.NewSessionHandler(sessionService)
+		summaryConsumer  = service.NewSummaryConsumerService(sessionRepo, notificationRepo, userRepo, zapLogger, rabbitConn, wsService, messagePersister)
 
 		// Message
-		messageService = service.NewMessageService(messageRepo, sessionRepo, userRepo, zapLogger, wsService, jwt, redisClient)
+		messageService = service.NewMessageService(messageRepo, sessionRepo, userRepo, zapLogger, wsService, jwt, redisClient, participantService, notificationRepo, attachmentRepo, rateLimitService)
 		messageHandler = handler.NewMessageHandler(messageService)
 
+		// Search
+		searchService = service.NewSearchService(messageRepo, sessionRepo, userRepo, zapLogger, jwt)
+		searchHandler = handler.NewSearchHandler(searchService)
+
+		// Websocket commands
+		wsHandler = handler.NewWebsocketHandler(wsService, messageService, sessionService, participantService)
+
+		// Event stream (SSE)
+		streamTicketRepo    = repository.NewStreamTicketRepository(redisClient)
+		streamTicketService = service.NewStreamTicketService(streamTicketRepo, zapLogger, jwt)
+		eventHandler        = handler.NewEventHandler(wsService, participantService, streamTicketService)
+
 		// Schedule
 		scheduleRepo    = repository.NewScheduleRepository(db)
-		scheduleService = service.NewScheduleService(scheduleRepo, userRepo, zapLogger, jwt)
+		scheduleService = service.NewScheduleService(scheduleRepo, userRepo, zapLogger, jwt, participantService)
 		scheduleHandler = handler.NewScheduleHandler(scheduleService)
 	)
 
+	// Websocket frame handlers
+	wsHandler.RegisterCommands()
+	wsService.RegisterConnectionListener(presenceService)
+
+	// Background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go messagePersister.Run(workerCtx)
+	go summaryConsumer.Run(workerCtx)
+	go outboxRelay.Run(workerCtx)
+	go wsService.Run(workerCtx)
+
 	server := gin.Default()
 	server.Use(middleware.CORSMiddleware())
 
 	// Websocket route
 	server.GET("/ws", wsService.HandleWebSocket)
+	// metrics koneksi data operasional, pakai token ops (OPS_METRICS_TOKEN) bukan jwt user
+	server.GET("/ws/metrics", middleware.OpsToken("OPS_METRICS_TOKEN"), wsHandler.Metrics)
 	// Other route
 	routes.Auth(server, authHandler, jwt)
-	routes.File(server, fileHandler, jwt)
+	routes.File(server, fileHandler, jwt, rateLimitService)
 	routes.User(server, userHandler, jwt)
 	routes.Thesis(server, thesisHandler, jwt)
+	routes.Presence(server, presenceHandler, jwt)
+	routes.Event(server, eventHandler, jwt, streamTicketService)
 	routes.Notification(server, notificationHandler, jwt)
 	routes.Session(server, sessionHandler, jwt)
-	routes.Message(server, messageHandler, jwt)
+	routes.Message(server, messageHandler, jwt, rateLimitService)
+	routes.Search(server, searchHandler, jwt)
 	routes.Schedule(server, scheduleHandler, jwt)
 
 	server.Static("/uploads", "./uploads")
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/response"
+	"github.com/gin-gonic/gin"
+)
+
+// OpsToken endpoint operasional (metrics dll) hanya bisa diakses dengan token ops dari env, terpisah dari
+// jwt user. kalau env kosong endpoint selalu ditolak
+func OpsToken(envKey string) gin.HandlerFunc {
+	expected := strings.TrimSpace(os.Getenv(envKey))
+
+	return func(ctx *gin.Context) {
+		token := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if expected == "" || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			res := response.BuildResponseFailed(dto.MESSAGE_FAILED_PROSES_REQUEST, dto.MESSAGE_FAILED_ACCESS_DENIED, nil)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, res)
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit token bucket per user untuk satu rule, harus dipasang setelah Authentication
+func RateLimit(rateLimitService service.IRateLimitService, jwtService jwt.IJWT, rule string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := ctx.GetString("user_id")
+		role, err := jwtService.GetUserRoleByToken(ctx.GetString("Authorization"))
+		if err != nil {
+			role = ""
+		}
+
+		result, err := rateLimitService.Allow(ctx, rule, userID, role)
+		if result != nil {
+			for key, value := range result.Headers() {
+				ctx.Header(key, value)
+			}
+		}
+		if err != nil {
+			res := response.BuildResponseFailed(dto.MESSAGE_FAILED_PROSES_REQUEST, err.Error(), nil)
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, res)
+			return
+		}
+
+		ctx.Next()
+	}
+}
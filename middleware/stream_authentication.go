@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/response"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamAuthentication untuk endpoint sse: header Authorization seperti biasa, atau ?ticket= untuk EventSource
+// di browser yang tidak bisa set header
+func StreamAuthentication(jwtService jwt.IJWT, streamTicketService service.IStreamTicketService) gin.HandlerFunc {
+	authentication := Authentication(jwtService)
+
+	return func(ctx *gin.Context) {
+		ticket := ctx.Query("ticket")
+		if ctx.GetHeader("Authorization") != "" || ticket == "" {
+			authentication(ctx)
+			return
+		}
+
+		token, userID, err := streamTicketService.RedeemTicket(ctx, ticket)
+		if err != nil {
+			res := response.BuildResponseFailed(dto.MESSAGE_FAILED_PROSES_REQUEST, err.Error(), nil)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, res)
+			return
+		}
+
+		ctx.Set("Authorization", token)
+		ctx.Set("user_id", userID)
+		ctx.Next()
+	}
+}
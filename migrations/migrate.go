@@ -18,11 +18,103 @@ func Migrate(db *gorm.DB) error {
 		&entity.ThesisLog{},
 		&entity.Session{},
 		&entity.Message{},
+		&entity.MessageEdit{},
+		&entity.MessagePin{},
+		&entity.Attachment{},
+		&entity.ReadCursor{},
 		&entity.Note{},
 		&entity.Schedule{},
+		&entity.Outbox{},
 	); err != nil {
 		return err
 	}
 
+	// full-text search message (bahasa indonesia + inggris). kolom generated tidak didaftarkan di entity
+	// supaya tidak pernah ditulis oleh gorm
+	if err := db.Exec(`
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			to_tsvector('indonesian', coalesce(text, '')) || to_tsvector('english', coalesce(text, ''))
+		) STORED`).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN (search_vector)`).Error; err != nil {
+		return err
+	}
+
+	// backfill seq dijalankan sekali saja, kalau diulang setiap start seq yang sudah dipakai client dan
+	// zset redis bisa ikut bergeser
+	if err := runOnce(db, "20260101_messages_seq_backfill", func(tx *gorm.DB) error {
+		// message lama (sebelum ada seq) dan session yang seq-nya bentrok diurutkan ulang sesuai waktu kirim
+		if err := tx.Exec(`
+			UPDATE messages m SET seq = s.rn
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY session_id ORDER BY created_at, id) AS rn
+				FROM messages
+				WHERE session_id IN (
+					SELECT session_id FROM messages
+					GROUP BY session_id
+					HAVING MIN(seq) = 0 OR COUNT(*) <> COUNT(DISTINCT seq)
+				)
+			) s
+			WHERE m.id = s.id`).Error; err != nil {
+			return err
+		}
+
+		// unique index dibuat setelah backfill, index lama (non unique) dihapus
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_messages_session_seq`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_session_seq_unique ON messages (session_id, seq)`).Error
+	}); err != nil {
+		return err
+	}
+
+	// cursor lama hanya punya last_read_at, seq diambil dari message yang ditandai dibaca atau seq terakhir
+	// sebelum waktu baca kalau message-nya belum dipersist
+	if err := runOnce(db, "20260102_read_cursors_last_read_seq", func(tx *gorm.DB) error {
+		return tx.Exec(`
+			UPDATE read_cursors rc SET last_read_seq = COALESCE(
+				(SELECT m.seq FROM messages m WHERE m.id = rc.last_read_message_id),
+				(SELECT MAX(m.seq) FROM messages m WHERE m.session_id = rc.session_id AND m.created_at <= rc.last_read_at),
+				0
+			)
+			WHERE rc.last_read_seq = 0`).Error
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// runOnce menjalankan migrasi data yang tidak boleh diulang, versi yang sudah jalan dicatat di schema_migrations
+func runOnce(db *gorm.DB, version string, fn func(tx *gorm.DB) error) error {
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// lock supaya dua instance yang start bersamaan tidak menjalankan migrasi yang sama
+		if err := tx.Exec(`LOCK TABLE schema_migrations IN EXCLUSIVE MODE`).Error; err != nil {
+			return err
+		}
+
+		var applied bool
+		if err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, version).Scan(&applied).Error; err != nil {
+			return err
+		}
+		if applied {
+			return nil
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version).Error
+	})
+}
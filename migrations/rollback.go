@@ -7,8 +7,13 @@ import (
 
 func Rollback(db *gorm.DB) error {
 	tables := []interface{}{
+		&entity.Outbox{},
 		&entity.Schedule{},
 		&entity.Note{},
+		&entity.ReadCursor{},
+		&entity.Attachment{},
+		&entity.MessageEdit{},
+		&entity.MessagePin{},
 		&entity.Message{},
 		&entity.Session{},
 		&entity.ThesisLog{},
@@ -28,5 +33,9 @@ func Rollback(db *gorm.DB) error {
 		}
 	}
 
+	if err := db.Exec(`DROP TABLE IF EXISTS schema_migrations`).Error; err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Amierza/chat-service/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	IAttachmentRepository interface {
+		// CREATE / POST
+		CreateAttachments(ctx context.Context, tx *gorm.DB, attachments []entity.Attachment) error
+
+		// READ / GET
+		GetAttachmentsByIDs(ctx context.Context, tx *gorm.DB, ids []uuid.UUID) ([]entity.Attachment, error)
+		GetAttachmentsByMessageIDs(ctx context.Context, tx *gorm.DB, messageIDs []uuid.UUID) ([]entity.Attachment, error)
+
+		// UPDATE / PATCH
+		LinkAttachments(ctx context.Context, tx *gorm.DB, ids []uuid.UUID, uploaderID, messageID, sessionID uuid.UUID) (int64, error)
+
+		// DELETE / DELETE
+	}
+
+	attachmentRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewAttachmentRepository(db *gorm.DB) *attachmentRepository {
+	return &attachmentRepository{
+		db: db,
+	}
+}
+
+// CREATE / POST
+func (ar *attachmentRepository) CreateAttachments(ctx context.Context, tx *gorm.DB, attachments []entity.Attachment) error {
+	if tx == nil {
+		tx = ar.db
+	}
+
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Omit(clause.Associations).Create(&attachments).Error
+}
+
+// READ / GET
+func (ar *attachmentRepository) GetAttachmentsByIDs(ctx context.Context, tx *gorm.DB, ids []uuid.UUID) ([]entity.Attachment, error) {
+	if tx == nil {
+		tx = ar.db
+	}
+
+	var attachments []entity.Attachment
+	if err := tx.WithContext(ctx).
+		Where("id IN ?", ids).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+func (ar *attachmentRepository) GetAttachmentsByMessageIDs(ctx context.Context, tx *gorm.DB, messageIDs []uuid.UUID) ([]entity.Attachment, error) {
+	if tx == nil {
+		tx = ar.db
+	}
+
+	var attachments []entity.Attachment
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	if err := tx.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order(`"created_at" ASC`).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// UPDATE / PATCH
+func (ar *attachmentRepository) LinkAttachments(ctx context.Context, tx *gorm.DB, ids []uuid.UUID, uploaderID, messageID, sessionID uuid.UUID) (int64, error) {
+	if tx == nil {
+		tx = ar.db
+	}
+
+	// hanya attachment milik uploader yang belum dipakai message lain, jumlah baris yang ter-update
+	// dicek oleh service supaya request bersamaan tidak bisa memakai file yang sama
+	result := tx.WithContext(ctx).
+		Model(&entity.Attachment{}).
+		Where("id IN ? AND uploader_id = ? AND message_id IS NULL", ids, uploaderID).
+		Updates(map[string]interface{}{
+			"message_id": messageID,
+			"session_id": sessionID,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// DELETE / DELETE
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// eventLogMaxLen jumlah event terakhir yang disimpan per user, sisanya dibuang dari yang paling lama
+	eventLogMaxLen = 500
+	eventLogTTL    = 3 * 24 * time.Hour
+	eventSeqKey    = "events:seq"
+)
+
+type (
+	IEventLogRepository interface {
+		// CREATE / POST
+		NextEventID(ctx context.Context) (int64, error)
+		AppendEvent(ctx context.Context, userIDs []string, entry dto.WSEventLogEntry) error
+
+		// READ / GET
+		GetEventsAfter(ctx context.Context, userID string, lastEventID int64) ([]dto.WSEventLogEntry, error)
+
+		// UPDATE / PATCH
+
+		// DELETE / DELETE
+	}
+
+	eventLogRepository struct {
+		redis *redis.Client
+	}
+)
+
+func NewEventLogRepository(redis *redis.Client) *eventLogRepository {
+	return &eventLogRepository{
+		redis: redis,
+	}
+}
+
+// setiap user punya sorted set event dengan score = event id global, jadi event yang sama
+// (contoh: broadcast ke room) punya id yang sama di log semua penerimanya
+func eventLogKey(userID string) string {
+	return fmt.Sprintf("events:%s", userID)
+}
+
+// CREATE / POST
+func (er *eventLogRepository) NextEventID(ctx context.Context) (int64, error) {
+	id, err := er.redis.Incr(ctx, eventSeqKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate event id: %w", err)
+	}
+
+	return id, nil
+}
+
+func (er *eventLogRepository) AppendEvent(ctx context.Context, userIDs []string, entry dto.WSEventLogEntry) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event log entry: %w", err)
+	}
+
+	pipe := er.redis.TxPipeline()
+	for _, userID := range userIDs {
+		key := eventLogKey(userID)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(entry.ID), Member: data})
+		pipe.ZRemRangeByRank(ctx, key, 0, -(eventLogMaxLen + 1))
+		pipe.Expire(ctx, key, eventLogTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append event log: %w", err)
+	}
+
+	return nil
+}
+
+// READ / GET
+func (er *eventLogRepository) GetEventsAfter(ctx context.Context, userID string, lastEventID int64) ([]dto.WSEventLogEntry, error) {
+	raws, err := er.redis.ZRangeByScore(ctx, eventLogKey(userID), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(lastEventID, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event log: %w", err)
+	}
+
+	entries := make([]dto.WSEventLogEntry, 0, len(raws))
+	for _, raw := range raws {
+		var entry dto.WSEventLogEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
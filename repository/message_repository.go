@@ -2,34 +2,125 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/Amierza/chat-service/constants"
 	"github.com/Amierza/chat-service/dto"
 	"github.com/Amierza/chat-service/entity"
-	"github.com/Amierza/chat-service/response"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// messageSearchScope message session finished milik thesis yang dimiliki / dibimbing user. session live
+// dicari langsung di redis karena message-nya belum tentu sudah dipersist
+const messageSearchScope = `
+	FROM messages m
+	JOIN sessions s ON s.id = m.session_id AND s.deleted_at IS NULL
+	JOIN theses t ON t.id = s.thesis_id AND t.deleted_at IS NULL
+	JOIN users u ON u.id = m.sender_id
+	LEFT JOIN students st ON st.id = u.student_id
+	LEFT JOIN lecturers l ON l.id = u.lecturer_id,
+	websearch_to_tsquery('indonesian', @query) AS q_id,
+	websearch_to_tsquery('english', @query) AS q_en
+	WHERE m.deleted_at IS NULL AND m.is_deleted = false
+		AND s.status = @status
+		AND m.search_vector @@ (q_id || q_en)
+		AND (
+			t.student_id = @student_id
+			OR t.id IN (SELECT ts.thesis_id FROM thesis_supervisors ts WHERE ts.lecturer_id = @lecturer_id AND ts.deleted_at IS NULL)
+		)`
+
+// replaceMemberScript ganti member zset secara atomic dengan score lama dipertahankan supaya urutan chat dan
+// checkpoint persister tidak berubah. return 0 kalau member lama sudah tidak ada
+var replaceMemberScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score then
+	return 0
+end
+
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[1], score, ARGV[2])
+return 1
+`)
+
+// legacyMessageScore score message sebelum ada seq masih berupa UnixNano, seq tidak akan pernah sebesar ini
+const legacyMessageScore = 1e15
+
+// nextSeqScript seed (kalau key belum ada) dan INCR dalam satu langkah atomic. seed = nilai terbesar antara
+// ARGV[1] (seq terakhir di postgres) dan score message yang masih di redis. ARGV[1] = -1 berarti caller belum
+// punya seed, script return 0 kalau key belum ada supaya caller mengambil seed dulu
+var nextSeqScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	local seed = tonumber(ARGV[1])
+	if seed < 0 then
+		return 0
+	end
+	local top = redis.call('ZREVRANGEBYSCORE', KEYS[2], '(' .. ARGV[3], '-inf', 'WITHSCORES', 'LIMIT', 0, 1)
+	if top[2] ~= nil and tonumber(top[2]) > seed then
+		seed = tonumber(top[2])
+	end
+	redis.call('SET', KEYS[1], seed)
+end
+
+local seq = redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+return seq
+`)
+
+const messageHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2"
+
 type (
 	IMessageRepository interface {
 		// CREATE / POST
 		CreateMessage(ctx context.Context, tx *gorm.DB, message *entity.Message) error
+		UpsertMessages(ctx context.Context, tx *gorm.DB, messages []entity.Message) error
+		CreateMessageEdit(ctx context.Context, tx *gorm.DB, edit *entity.MessageEdit) error
+		UpsertReadCursor(ctx context.Context, tx *gorm.DB, cursor *entity.ReadCursor) error
+		CreateMessagePin(ctx context.Context, tx *gorm.DB, pin *entity.MessagePin) (bool, error)
+		NextMessageSeq(ctx context.Context, tx *gorm.DB, sessionID string) (int64, error)
+		AddMessageToRedis(ctx context.Context, tx *gorm.DB, sessionID string, messageID uuid.UUID, seq int64, data []byte) error
 
 		// READ / GET
-		GetAllMessageFromRedisWithPagination(ctx context.Context, tx *gorm.DB, req response.PaginationRequest, session *entity.Session) (*dto.MessagePaginationRepositoryResponse, error)
+		GetAllMessageFromRedisWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error)
 		GetAllMessageFromRedis(ctx context.Context, tx *gorm.DB, session *entity.Session) (*[]dto.MessageEventPublish, error)
-		GetAllMessageWithPagination(ctx context.Context, tx *gorm.DB, req response.PaginationRequest, session *entity.Session) (*dto.MessagePaginationRepositoryResponse, error)
+		GetMessageFromRedisAfterSeq(ctx context.Context, tx *gorm.DB, sessionID string, seq int64) ([]dto.MessageEventPublish, int64, error)
+		GetAllMessageWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error)
+		GetRepliesFromRedisWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, parentID uuid.UUID, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error)
+		GetRepliesWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, parentID uuid.UUID, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error)
+		GetThreadStatsFromRedis(ctx context.Context, tx *gorm.DB, sessionID string) (map[uuid.UUID]dto.ThreadStats, error)
+		GetThreadStats(ctx context.Context, tx *gorm.DB, sessionID string, parentIDs []uuid.UUID) (map[uuid.UUID]dto.ThreadStats, error)
+		GetPersistCheckpoint(ctx context.Context, tx *gorm.DB, sessionID string) (int64, error)
+		SearchMessages(ctx context.Context, tx *gorm.DB, user *entity.User, query string, offset, limit int) ([]dto.MessageSearchRow, int64, error)
+		GetMessageContext(ctx context.Context, tx *gorm.DB, sessionID uuid.UUID, seq int64, size int) ([]entity.Message, []entity.Message, error)
+		GetMessageFromRedisByID(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*dto.MessageEventPublish, *redis.Z, bool, error)
+		GetMessageByID(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*entity.Message, bool, error)
+		GetReadCursor(ctx context.Context, tx *gorm.DB, sessionID, userID string) (*entity.ReadCursor, bool, error)
+		GetMessagePinsBySessionID(ctx context.Context, tx *gorm.DB, sessionID string) ([]entity.MessagePin, error)
+		GetMessagePin(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*entity.MessagePin, bool, error)
+		GetMessagesByIDs(ctx context.Context, tx *gorm.DB, sessionID string, messageIDs []uuid.UUID) ([]entity.Message, error)
+		GetMessageIDsBySeqs(ctx context.Context, tx *gorm.DB, sessionID string, seqs []int64) (map[int64]uuid.UUID, error)
+		GetReadCursorsBySessionIDs(ctx context.Context, tx *gorm.DB, userID string, sessionIDs []string) ([]entity.ReadCursor, error)
+		CountUnreadMessages(ctx context.Context, tx *gorm.DB, userID string, sessionIDs []string) (map[string]int64, error)
+		CountUnreadMessagesFromRedis(ctx context.Context, tx *gorm.DB, userID string, afters map[string]int64) (map[string]int64, error)
 
 		// UPDATE / PATCH
+		SetPersistCheckpoint(ctx context.Context, tx *gorm.DB, sessionID string, seq int64) error
+		RescoreLegacyMessages(ctx context.Context, tx *gorm.DB, sessionID string) (int, error)
+		ReplaceMessageInRedis(ctx context.Context, tx *gorm.DB, sessionID string, old *redis.Z, message *dto.MessageEventPublish) (bool, error)
+		UpdateMessage(ctx context.Context, tx *gorm.DB, message *entity.Message) error
 
 		// DELETE / DELETE
+		DeleteMessagePin(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (bool, error)
+		RemoveMessageFromRedis(ctx context.Context, tx *gorm.DB, sessionID string, messageID uuid.UUID, data []byte) error
 	}
 
 	messageRepository struct {
@@ -55,46 +146,167 @@ func (mr *messageRepository) CreateMessage(ctx context.Context, tx *gorm.DB, mes
 
 	return tx.WithContext(ctx).Create(&message).Error
 }
+func (mr *messageRepository) UpsertMessages(ctx context.Context, tx *gorm.DB, messages []entity.Message) error {
+	if tx == nil {
+		tx = mr.db
+	}
 
-// READ / GET
-func (mr *messageRepository) GetAllMessageFromRedisWithPagination(ctx context.Context, tx *gorm.DB, req response.PaginationRequest, session *entity.Session) (*dto.MessagePaginationRepositoryResponse, error) {
-	if req.PerPage == 0 {
-		req.PerPage = 10
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// message yang sudah pernah disimpan cukup di-update kolom yang bisa berubah (edit / hapus, seq
+	// hasil migrasi score lama), jadi aman dipanggil berulang kali
+	return tx.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"seq", "text", "file_url", "mentions", "is_edited", "edited_at", "is_deleted", "updated_at"}),
+		}).
+		Create(&messages).Error
+}
+func (mr *messageRepository) CreateMessageEdit(ctx context.Context, tx *gorm.DB, edit *entity.MessageEdit) error {
+	if tx == nil {
+		tx = mr.db
 	}
-	if req.Page == 0 {
-		req.Page = 1
+
+	return tx.WithContext(ctx).Create(&edit).Error
+}
+func (mr *messageRepository) UpsertReadCursor(ctx context.Context, tx *gorm.DB, cursor *entity.ReadCursor) error {
+	if tx == nil {
+		tx = mr.db
 	}
 
-	start := int64((req.Page - 1) * req.PerPage)
-	end := start + int64(req.PerPage) - 1
+	// cursor hanya boleh maju, mark read ke message dengan seq lebih kecil diabaikan. dibandingkan lewat seq
+	// bukan waktu supaya clock skew antar replica tidak membuat cursor mundur
+	return tx.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "session_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_read_message_id", "last_read_seq", "last_read_at", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "read_cursors.last_read_seq < excluded.last_read_seq"},
+			}},
+		}).
+		Create(&cursor).Error
+}
+
+// CreateMessagePin false kalau message sudah di-pin sebelumnya
+func (mr *messageRepository) CreateMessagePin(ctx context.Context, tx *gorm.DB, pin *entity.MessagePin) (bool, error) {
+	if tx == nil {
+		tx = mr.db
+	}
 
-	key := fmt.Sprintf("session:%s:messages", session.ID)
+	res := tx.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "session_id"}, {Name: "message_id"}},
+			DoNothing: true,
+		}).
+		Create(&pin)
+	if res.Error != nil {
+		return false, res.Error
+	}
 
-	// Get total count
-	count, err := mr.redis.ZCard(ctx, key).Result()
+	return res.RowsAffected > 0, nil
+}
+
+// messageIDsKey hash message id -> seq di samping zset messages, supaya cari message by id cukup
+// HGET + ZRANGEBYSCORE tanpa decode seluruh zset
+func messageIDsKey(sessionID string) string {
+	return fmt.Sprintf("session:%s:message_ids", sessionID)
+}
+
+// AddMessageToRedis ZADD message dengan score = seq sekaligus mencatat index id -> seq
+func (mr *messageRepository) AddMessageToRedis(ctx context.Context, tx *gorm.DB, sessionID string, messageID uuid.UUID, seq int64, data []byte) error {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+	idsKey := messageIDsKey(sessionID)
+
+	pipe := mr.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(seq), // seq masih jauh di bawah 2^53, jadi tetap presisi sebagai score
+		Member: data,
+	})
+	pipe.HSet(ctx, idsKey, messageID.String(), seq)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	pipe.Expire(ctx, idsKey, 24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add message to redis: %w", err)
+	}
+
+	return nil
+}
+
+// NextMessageSeq seq message per session dari INCR redis, selalu naik walaupun jam antar instance berbeda.
+// kalau key hilang (expired / redis restart) dilanjutkan dari seq terbesar yang sudah dipersist
+func (mr *messageRepository) NextMessageSeq(ctx context.Context, tx *gorm.DB, sessionID string) (int64, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	keys := []string{
+		fmt.Sprintf("session:%s:seq", sessionID),
+		fmt.Sprintf("session:%s:messages", sessionID),
+	}
+	ttl := int64((24 * time.Hour).Seconds()) // TTL disamakan dengan key messages
+
+	seq, err := nextSeqScript.Run(ctx, mr.redis, keys, -1, ttl, int64(legacyMessageScore)).Int64()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get message count from redis: %w", err)
+		return 0, fmt.Errorf("failed to increment message seq: %w", err)
+	}
+	if seq > 0 {
+		return seq, nil
 	}
 
-	if count == 0 {
-		return &dto.MessagePaginationRepositoryResponse{
-			Messages: []entity.Message{},
-			PaginationResponse: response.PaginationResponse{
-				Page:    req.Page,
-				PerPage: req.PerPage,
-				MaxPage: 0,
-				Count:   0,
-			},
-		}, nil
+	// key seq hilang, seed dari postgres lalu jalankan ulang script. seed + INCR tetap satu langkah atomic
+	// jadi instance lain yang seed bersamaan tidak bisa membuat seq dobel
+	var last int64
+	if err := tx.WithContext(ctx).
+		Model(&entity.Message{}).
+		Where("session_id = ?", sessionID).
+		Select("COALESCE(MAX(seq), 0)").
+		Scan(&last).Error; err != nil {
+		return 0, err
 	}
 
-	// Ambil data dari Redis (terbaru ke terlama)
-	results, err := mr.redis.ZRange(ctx, key, start, end).Result()
+	seq, err = nextSeqScript.Run(ctx, mr.redis, keys, last, ttl, int64(legacyMessageScore)).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment message seq: %w", err)
+	}
+
+	return seq, nil
+}
+
+// READ / GET
+func (mr *messageRepository) GetAllMessageFromRedisWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error) {
+	key := fmt.Sprintf("session:%s:messages", session.ID)
+
+	// score = seq message, jadi cursor bisa langsung dipakai sebagai batas eksklusif
+	by := &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "+inf",
+		Count: int64(limit + 1),
+	}
+	if cursor != nil {
+		if after {
+			by.Min = "(" + strconv.FormatInt(cursor.Seq, 10)
+		} else {
+			by.Max = "(" + strconv.FormatInt(cursor.Seq, 10)
+		}
+	}
+
+	var results []string
+	var err error
+	if after {
+		results, err = mr.redis.ZRangeByScore(ctx, key, by).Result()
+	} else {
+		results, err = mr.redis.ZRevRangeByScore(ctx, key, by).Result()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages from redis: %w", err)
 	}
 
-	var messages []entity.Message
+	messages := make([]entity.Message, 0, limit+1)
 	for _, raw := range results {
 		var evt dto.MessageEventPublish
 		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
@@ -102,59 +314,17 @@ func (mr *messageRepository) GetAllMessageFromRedisWithPagination(ctx context.Co
 			continue
 		}
 
-		log.Println(evt.Timestamp)
-		parsedTime, err := time.Parse(time.RFC3339Nano, evt.Timestamp)
-		if err != nil {
-			mr.logger.Warn("failed to parse timestamp", zap.String("timestamp", evt.Timestamp), zap.Error(err))
-		}
-
-		msg := entity.Message{
-			ID:      evt.MessageID,
-			IsText:  *evt.IsText,
-			Text:    evt.Text,
-			FileURL: evt.FileURL,
-			Sender: entity.User{
-				ID:         evt.Sender.ID,
-				Identifier: evt.Sender.Identifier,
-				Role:       entity.Role(evt.Sender.Role),
-			},
-			SessionID:       evt.SessionID,
-			ParentMessageID: evt.ParentMessageID,
-			TimeStamp: entity.TimeStamp{
-				CreatedAt: parsedTime,
-			},
-		}
-
-		if evt.Sender.Role == constants.ENUM_ROLE_STUDENT {
-			msg.Sender.StudentID = &session.Thesis.Student.ID
-			msg.Sender.Student.Name = session.Thesis.Student.Name
-			msg.Sender.Student.Nim = session.Thesis.Student.Nim
-		}
+		messages = append(messages, mr.toMessage(evt, session))
+	}
 
-		if evt.Sender.Role == constants.ENUM_ROLE_LECTURER {
-			for _, supervisor := range session.Thesis.Supervisors {
-				if evt.Sender.Identifier == supervisor.Lecturer.Nip {
-					msg.Sender.LecturerID = &supervisor.Lecturer.ID
-					msg.Sender.Lecturer.Name = supervisor.Lecturer.Name
-					msg.Sender.Lecturer.Nip = supervisor.Lecturer.Nip
-				}
-			}
+	// mode after diambil terlama ke terbaru, dibalik supaya urutan sama dengan mode before
+	if after {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
 		}
-
-		messages = append(messages, msg)
 	}
 
-	totalPage := int64(math.Ceil(float64(count) / float64(req.PerPage)))
-
-	return &dto.MessagePaginationRepositoryResponse{
-		Messages: messages,
-		PaginationResponse: response.PaginationResponse{
-			Page:    req.Page,
-			PerPage: req.PerPage,
-			MaxPage: totalPage,
-			Count:   count,
-		},
-	}, nil
+	return toCursorPage(messages, after, limit), nil
 }
 func (mr *messageRepository) GetAllMessageFromRedis(ctx context.Context, tx *gorm.DB, session *entity.Session) (*[]dto.MessageEventPublish, error) {
 	key := fmt.Sprintf("session:%s:messages", session.ID)
@@ -179,50 +349,874 @@ func (mr *messageRepository) GetAllMessageFromRedis(ctx context.Context, tx *gor
 
 	return &messages, nil
 }
-func (mr *messageRepository) GetAllMessageWithPagination(ctx context.Context, tx *gorm.DB, req response.PaginationRequest, session *entity.Session) (*dto.MessagePaginationRepositoryResponse, error) {
+func (mr *messageRepository) GetMessageFromRedisAfterSeq(ctx context.Context, tx *gorm.DB, sessionID string, seq int64) ([]dto.MessageEventPublish, int64, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+
+	results, err := mr.redis.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(seq, 10),
+		Max: "(" + strconv.FormatInt(legacyMessageScore, 10),
+	}).Result()
+	if err != nil {
+		return nil, seq, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+
+	lastSeq := seq
+	messages := make([]dto.MessageEventPublish, 0, len(results))
+	for _, z := range results {
+		raw, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+
+		var evt dto.MessageEventPublish
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			mr.logger.Warn("failed to unmarshal redis message", zap.Error(err))
+			continue
+		}
+
+		messages = append(messages, evt)
+		if int64(z.Score) > lastSeq {
+			lastSeq = int64(z.Score)
+		}
+	}
+
+	return messages, lastSeq, nil
+}
+func (mr *messageRepository) GetAllMessageWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error) {
 	if tx == nil {
 		tx = mr.db
 	}
 
+	query := tx.WithContext(ctx).
+		Model(&entity.Message{}).
+		Preload("Sender.Student.StudyProgram.Faculty").
+		Preload("Sender.Lecturer.StudyProgram.Faculty").
+		Where("session_id = ?", session.ID)
+
+	return findWithCursor(query, cursor, after, limit)
+}
+func (mr *messageRepository) GetRepliesFromRedisWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, parentID uuid.UUID, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error) {
+	key := fmt.Sprintf("session:%s:messages", session.ID)
+
+	// reply tersebar di seluruh session, jadi tidak bisa dibatasi lewat score seperti list biasa
+	results, err := mr.redis.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+
 	var messages []entity.Message
-	var err error
-	var count int64
+	for _, raw := range results {
+		var evt dto.MessageEventPublish
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			mr.logger.Warn("failed to unmarshal redis message", zap.Error(err))
+			continue
+		}
+		if evt.ParentMessageID == nil || *evt.ParentMessageID != parentID {
+			continue
+		}
+
+		if cursor != nil {
+			if after && evt.Seq <= cursor.Seq {
+				continue
+			}
+			if !after && evt.Seq >= cursor.Seq {
+				continue
+			}
+		}
+
+		messages = append(messages, mr.toMessage(evt, session))
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Seq > messages[j].Seq
+	})
 
-	if req.PerPage == 0 {
-		req.PerPage = 10
+	// sama dengan hasil query postgres: limit+1 terlama untuk mode after, limit+1 terbaru untuk mode before
+	if len(messages) > limit+1 {
+		if after {
+			messages = messages[len(messages)-limit-1:]
+		} else {
+			messages = messages[:limit+1]
+		}
 	}
 
-	if req.Page == 0 {
-		req.Page = 1
+	return toCursorPage(messages, after, limit), nil
+}
+func (mr *messageRepository) GetRepliesWithCursor(ctx context.Context, tx *gorm.DB, session *entity.Session, parentID uuid.UUID, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error) {
+	if tx == nil {
+		tx = mr.db
 	}
 
 	query := tx.WithContext(ctx).
 		Model(&entity.Message{}).
 		Preload("Sender.Student.StudyProgram.Faculty").
 		Preload("Sender.Lecturer.StudyProgram.Faculty").
-		Where("session_id = ?", session.ID)
+		Where("session_id = ? AND parent_message_id = ?", session.ID, parentID)
+
+	return findWithCursor(query, cursor, after, limit)
+}
+func (mr *messageRepository) GetThreadStatsFromRedis(ctx context.Context, tx *gorm.DB, sessionID string) (map[uuid.UUID]dto.ThreadStats, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
 
-	if err := query.Count(&count).Error; err != nil {
+	results, err := mr.redis.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+
+	// hasil ZRange urut seq, reply terakhir cukup yang paling akhir ditemukan
+	stats := make(map[uuid.UUID]dto.ThreadStats)
+	for _, raw := range results {
+		var evt dto.MessageEventPublish
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			continue
+		}
+		if evt.ParentMessageID == nil || evt.IsDeleted {
+			continue
+		}
+
+		parentID := *evt.ParentMessageID
+		stat := stats[parentID]
+		stat.ReplyCount++
+		stat.LastReply = &dto.ThreadLastReply{
+			ID:        evt.MessageID,
+			Seq:       evt.Seq,
+			Text:      evt.Text,
+			Sender:    evt.Sender,
+			Timestamp: evt.Timestamp,
+		}
+		stats[parentID] = stat
+	}
+
+	return stats, nil
+}
+func (mr *messageRepository) GetThreadStats(ctx context.Context, tx *gorm.DB, sessionID string, parentIDs []uuid.UUID) (map[uuid.UUID]dto.ThreadStats, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	stats := make(map[uuid.UUID]dto.ThreadStats)
+	if len(parentIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []dto.ThreadStatsRow
+	err := tx.WithContext(ctx).Raw(`
+		SELECT r.parent_message_id, r.reply_count, r.id, r.seq, r.sender_id, u.role AS sender_role,
+			COALESCE(st.name, l.name) AS sender_name, COALESCE(st.nim, l.nip) AS sender_identifier,
+			r.text, r.created_at
+		FROM (
+			SELECT m.id, m.seq, m.parent_message_id, m.sender_id, m.text, m.created_at,
+				COUNT(*) OVER (PARTITION BY m.parent_message_id) AS reply_count,
+				ROW_NUMBER() OVER (PARTITION BY m.parent_message_id ORDER BY m.seq DESC) AS rn
+			FROM messages m
+			WHERE m.session_id = @session_id AND m.parent_message_id IN @parent_ids
+				AND m.deleted_at IS NULL AND m.is_deleted = false
+		) r
+		JOIN users u ON u.id = r.sender_id
+		LEFT JOIN students st ON st.id = u.student_id
+		LEFT JOIN lecturers l ON l.id = u.lecturer_id
+		WHERE r.rn = 1`,
+		sql.Named("session_id", sessionID),
+		sql.Named("parent_ids", parentIDs),
+	).Scan(&rows).Error
+	if err != nil {
 		return nil, err
 	}
 
-	if err := query.Order(`"created_at" DESC`).Scopes(response.Paginate(req.Page, req.PerPage)).Find(&messages).Error; err != nil {
+	for _, row := range rows {
+		stats[row.ParentMessageID] = dto.ThreadStats{
+			ReplyCount: row.ReplyCount,
+			LastReply: &dto.ThreadLastReply{
+				ID:   row.ID,
+				Seq:  row.Seq,
+				Text: row.Text,
+				Sender: dto.CustomUserResponse{
+					ID:         row.SenderID,
+					Name:       row.SenderName,
+					Identifier: row.SenderIdentifier,
+					Role:       row.SenderRole,
+				},
+				Timestamp: row.CreatedAt.Format(time.RFC3339Nano),
+			},
+		}
+	}
+
+	return stats, nil
+}
+
+// findWithCursor menjalankan query message postgres dengan keyset seq, hasil terbaru ke terlama
+func findWithCursor(query *gorm.DB, cursor *dto.MessageCursor, after bool, limit int) (*dto.MessagePaginationRepositoryResponse, error) {
+	order := `"seq" DESC`
+	if after {
+		order = `"seq" ASC`
+	}
+	if cursor != nil {
+		if after {
+			query = query.Where("seq > ?", cursor.Seq)
+		} else {
+			query = query.Where("seq < ?", cursor.Seq)
+		}
+	}
+
+	var messages []entity.Message
+	if err := query.Order(order).Limit(limit + 1).Find(&messages).Error; err != nil {
 		return nil, err
 	}
 
-	totalPage := int64(math.Ceil(float64(count) / float64(req.PerPage)))
+	// mode after diambil terlama ke terbaru, dibalik supaya urutan response sama dengan mode before
+	if after {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return toCursorPage(messages, after, limit), nil
+}
+
+func (mr *messageRepository) SearchMessages(ctx context.Context, tx *gorm.DB, user *entity.User, query string, offset, limit int) ([]dto.MessageSearchRow, int64, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	args := []interface{}{
+		sql.Named("query", query),
+		sql.Named("status", constants.ENUM_SESSION_STATUS_FINSIHED),
+		sql.Named("student_id", user.StudentID),
+		sql.Named("lecturer_id", user.LecturerID),
+	}
+
+	var count int64
+	if err := tx.WithContext(ctx).Raw(`SELECT COUNT(*)`+messageSearchScope, args...).Scan(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if count == 0 {
+		return []dto.MessageSearchRow{}, 0, nil
+	}
+
+	// highlight memakai konfigurasi bahasa yang cocok dengan message
+	var rows []dto.MessageSearchRow
+	err := tx.WithContext(ctx).Raw(`
+		SELECT m.id AS message_id, m.seq, m.session_id, s.thesis_id, m.sender_id, u.role AS sender_role,
+			COALESCE(st.name, l.name) AS sender_name, COALESCE(st.nim, l.nip) AS sender_identifier,
+			m.text, m.created_at,
+			CASE WHEN to_tsvector('indonesian', m.text) @@ q_id
+				THEN ts_headline('indonesian', m.text, q_id, @options)
+				ELSE ts_headline('english', m.text, q_en, @options)
+			END AS highlight,
+			ts_rank(m.search_vector, q_id || q_en) AS rank`+messageSearchScope+`
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT @limit OFFSET @offset`,
+		append(args,
+			sql.Named("options", messageHeadlineOptions),
+			sql.Named("limit", limit),
+			sql.Named("offset", offset),
+		)...,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rows, count, nil
+}
+
+// GetMessageContext size message sebelum dan sesudah message tertentu di session yang sama (urut seq)
+func (mr *messageRepository) GetMessageContext(ctx context.Context, tx *gorm.DB, sessionID uuid.UUID, seq int64, size int) ([]entity.Message, []entity.Message, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	query := func() *gorm.DB {
+		return tx.WithContext(ctx).
+			Model(&entity.Message{}).
+			Preload("Sender.Student").
+			Preload("Sender.Lecturer").
+			Where("session_id = ? AND is_deleted = false", sessionID).
+			Limit(size)
+	}
+
+	var before []entity.Message
+	if err := query().
+		Where("seq < ?", seq).
+		Order(`"seq" DESC`).
+		Find(&before).Error; err != nil {
+		return nil, nil, err
+	}
+	for i, j := 0, len(before)-1; i < j; i, j = i+1, j-1 {
+		before[i], before[j] = before[j], before[i]
+	}
+
+	var after []entity.Message
+	if err := query().
+		Where("seq > ?", seq).
+		Order(`"seq" ASC`).
+		Find(&after).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+// toMessage mapping message live di redis ke entity, data sender diambil dari thesis session
+func (mr *messageRepository) toMessage(evt dto.MessageEventPublish, session *entity.Session) entity.Message {
+	parsedTime, err := time.Parse(time.RFC3339Nano, evt.Timestamp)
+	if err != nil {
+		mr.logger.Warn("failed to parse timestamp", zap.String("timestamp", evt.Timestamp), zap.Error(err))
+	}
+
+	msg := entity.Message{
+		ID:      evt.MessageID,
+		Seq:     evt.Seq,
+		IsText:  *evt.IsText,
+		Text:    evt.Text,
+		FileURL: evt.FileURL,
+		Sender: entity.User{
+			ID:         evt.Sender.ID,
+			Identifier: evt.Sender.Identifier,
+			Role:       entity.Role(evt.Sender.Role),
+		},
+		SessionID:       evt.SessionID,
+		ParentMessageID: evt.ParentMessageID,
+		ClientMessageID: evt.ClientMessageID,
+		Mentions:        evt.Mentions,
+		IsEdited:        evt.IsEdited,
+		IsDeleted:       evt.IsDeleted,
+		TimeStamp: entity.TimeStamp{
+			CreatedAt: parsedTime,
+		},
+	}
+	if evt.EditedAt != "" {
+		if editedAt, err := time.Parse(time.RFC3339Nano, evt.EditedAt); err == nil {
+			msg.EditedAt = &editedAt
+		}
+	}
+
+	if evt.Sender.Role == constants.ENUM_ROLE_STUDENT {
+		msg.Sender.StudentID = &session.Thesis.Student.ID
+		msg.Sender.Student.Name = session.Thesis.Student.Name
+		msg.Sender.Student.Nim = session.Thesis.Student.Nim
+	}
+
+	if evt.Sender.Role == constants.ENUM_ROLE_LECTURER {
+		for _, supervisor := range session.Thesis.Supervisors {
+			if evt.Sender.Identifier == supervisor.Lecturer.Nip {
+				msg.Sender.LecturerID = &supervisor.Lecturer.ID
+				msg.Sender.Lecturer.Name = supervisor.Lecturer.Name
+				msg.Sender.Lecturer.Nip = supervisor.Lecturer.Nip
+			}
+		}
+	}
+
+	return msg
+}
+
+// toCursorPage messages terbaru ke terlama berisi maksimal limit+1, kelebihan satu berarti masih ada halaman
+// berikutnya. mode after membuang yang paling baru, mode before membuang yang paling lama
+func toCursorPage(messages []entity.Message, after bool, limit int) *dto.MessagePaginationRepositoryResponse {
+	hasMore := len(messages) > limit
+	if hasMore {
+		if after {
+			messages = messages[len(messages)-limit:]
+		} else {
+			messages = messages[:limit]
+		}
+	}
 
 	return &dto.MessagePaginationRepositoryResponse{
 		Messages: messages,
-		PaginationResponse: response.PaginationResponse{
-			Page:    req.Page,
-			PerPage: req.PerPage,
-			MaxPage: totalPage,
-			Count:   count,
-		},
-	}, err
+		HasMore:  hasMore,
+	}
+}
+
+func (mr *messageRepository) GetPersistCheckpoint(ctx context.Context, tx *gorm.DB, sessionID string) (int64, error) {
+	key := fmt.Sprintf("session:%s:checkpoint", sessionID)
+
+	checkpoint, err := mr.redis.Get(ctx, key).Float64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get persist checkpoint from redis: %w", err)
+	}
+	// checkpoint lama berisi score UnixNano, mulai ulang dari awal (upsert tetap idempotent)
+	if checkpoint >= legacyMessageScore {
+		return 0, nil
+	}
+
+	return int64(checkpoint), nil
+}
+
+// GetMessageFromRedisByID seq dicari dari index id -> seq lalu member diambil dengan ZRANGEBYSCORE. session live
+// dari sebelum ada index di-scan sekali lalu index-nya dibangun
+func (mr *messageRepository) GetMessageFromRedisByID(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*dto.MessageEventPublish, *redis.Z, bool, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+	idsKey := messageIDsKey(sessionID)
+
+	seq, err := mr.redis.HGet(ctx, idsKey, messageID).Int64()
+	if errors.Is(err, redis.Nil) {
+		indexed, err := mr.redis.Exists(ctx, idsKey).Result()
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to get message index from redis: %w", err)
+		}
+		if indexed > 0 {
+			return nil, nil, false, nil
+		}
+		return mr.indexMessagesInRedis(ctx, sessionID, messageID)
+	}
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to get message index from redis: %w", err)
+	}
+
+	score := strconv.FormatInt(seq, 10)
+	results, err := mr.redis.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+	evt, z, found := findMessageMember(results, messageID)
+
+	return evt, z, found, nil
+}
+
+// indexMessagesInRedis scan seluruh zset sekali untuk membangun index id -> seq
+func (mr *messageRepository) indexMessagesInRedis(ctx context.Context, sessionID, messageID string) (*dto.MessageEventPublish, *redis.Z, bool, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+
+	results, err := mr.redis.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, nil, false, nil
+	}
+
+	index := make(map[string]interface{}, len(results))
+	for _, z := range results {
+		raw, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		var evt dto.MessageEventPublish
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			continue
+		}
+		index[evt.MessageID.String()] = int64(z.Score)
+	}
+
+	if len(index) > 0 {
+		ttl, err := mr.redis.TTL(ctx, key).Result()
+		if err != nil || ttl <= 0 {
+			ttl = 24 * time.Hour
+		}
+		pipe := mr.redis.TxPipeline()
+		pipe.HSet(ctx, messageIDsKey(sessionID), index)
+		pipe.Expire(ctx, messageIDsKey(sessionID), ttl)
+		if _, err := pipe.Exec(ctx); err != nil {
+			mr.logger.Warn("failed to build message index in redis",
+				zap.String("session_id", sessionID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	evt, z, found := findMessageMember(results, messageID)
+
+	return evt, z, found, nil
+}
+
+func findMessageMember(results []redis.Z, messageID string) (*dto.MessageEventPublish, *redis.Z, bool) {
+	for _, z := range results {
+		raw, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+
+		var evt dto.MessageEventPublish
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			continue
+		}
+
+		if evt.MessageID.String() == messageID {
+			return &evt, &z, true
+		}
+	}
+
+	return nil, nil, false
+}
+func (mr *messageRepository) GetMessageByID(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*entity.Message, bool, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var message entity.Message
+	err := tx.WithContext(ctx).
+		Preload("Sender.Student").
+		Preload("Sender.Lecturer").
+		Where("id = ? AND session_id = ?", messageID, sessionID).
+		Take(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entity.Message{}, false, nil
+	}
+	if err != nil {
+		return &entity.Message{}, false, err
+	}
+
+	return &message, true, nil
+}
+
+func (mr *messageRepository) GetReadCursor(ctx context.Context, tx *gorm.DB, sessionID, userID string) (*entity.ReadCursor, bool, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var cursor entity.ReadCursor
+	err := tx.WithContext(ctx).
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Take(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entity.ReadCursor{}, false, nil
+	}
+	if err != nil {
+		return &entity.ReadCursor{}, false, err
+	}
+
+	return &cursor, true, nil
+}
+
+// GetMessagePinsBySessionID urut dari yang paling awal di-pin
+func (mr *messageRepository) GetMessagePinsBySessionID(ctx context.Context, tx *gorm.DB, sessionID string) ([]entity.MessagePin, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var pins []entity.MessagePin
+	err := tx.WithContext(ctx).
+		Preload("PinnedBy.Lecturer").
+		Where("session_id = ?", sessionID).
+		Order("created_at ASC").
+		Find(&pins).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return pins, nil
+}
+
+func (mr *messageRepository) GetMessagePin(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (*entity.MessagePin, bool, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var pin entity.MessagePin
+	err := tx.WithContext(ctx).
+		Preload("PinnedBy.Lecturer").
+		Where("session_id = ? AND message_id = ?", sessionID, messageID).
+		Take(&pin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entity.MessagePin{}, false, nil
+	}
+	if err != nil {
+		return &entity.MessagePin{}, false, err
+	}
+
+	return &pin, true, nil
+}
+
+func (mr *messageRepository) GetMessagesByIDs(ctx context.Context, tx *gorm.DB, sessionID string, messageIDs []uuid.UUID) ([]entity.Message, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var messages []entity.Message
+	if len(messageIDs) == 0 {
+		return messages, nil
+	}
+	if err := tx.WithContext(ctx).
+		Preload("Sender.Student").
+		Preload("Sender.Lecturer").
+		Where("session_id = ? AND id IN ?", sessionID, messageIDs).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetMessageIDsBySeqs pemilik seq yang sudah tersimpan, termasuk message soft delete karena tetap kena unique index
+func (mr *messageRepository) GetMessageIDsBySeqs(ctx context.Context, tx *gorm.DB, sessionID string, seqs []int64) (map[int64]uuid.UUID, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	owners := make(map[int64]uuid.UUID, len(seqs))
+	if len(seqs) == 0 {
+		return owners, nil
+	}
+
+	var rows []entity.Message
+	if err := tx.WithContext(ctx).
+		Unscoped().
+		Select("id", "seq").
+		Where("session_id = ? AND seq IN ?", sessionID, seqs).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		owners[row.Seq] = row.ID
+	}
+
+	return owners, nil
+}
+
+func (mr *messageRepository) GetReadCursorsBySessionIDs(ctx context.Context, tx *gorm.DB, userID string, sessionIDs []string) ([]entity.ReadCursor, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	var cursors []entity.ReadCursor
+	if len(sessionIDs) == 0 {
+		return cursors, nil
+	}
+	if err := tx.WithContext(ctx).
+		Where("user_id = ? AND session_id IN ?", userID, sessionIDs).
+		Find(&cursors).Error; err != nil {
+		return nil, err
+	}
+
+	return cursors, nil
+}
+
+// CountUnreadMessages satu query grouped untuk banyak session, batas baca diambil dari read cursor user.
+// session tanpa message belum dibaca tidak ada di map
+func (mr *messageRepository) CountUnreadMessages(ctx context.Context, tx *gorm.DB, userID string, sessionIDs []string) (map[string]int64, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	res := make(map[string]int64, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return res, nil
+	}
+
+	var rows []struct {
+		SessionID string
+		Count     int64
+	}
+	if err := tx.WithContext(ctx).
+		Table("messages AS m").
+		Select("m.session_id, COUNT(*) AS count").
+		Joins("LEFT JOIN read_cursors AS rc ON rc.session_id = m.session_id AND rc.user_id = ?", userID).
+		Where("m.session_id IN ? AND m.sender_id <> ? AND m.is_deleted = ? AND m.deleted_at IS NULL", sessionIDs, userID, false).
+		Where("rc.last_read_seq IS NULL OR m.seq > rc.last_read_seq").
+		Group("m.session_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		res[row.SessionID] = row.Count
+	}
+
+	return res, nil
+}
+
+// CountUnreadMessagesFromRedis afters berisi last read seq per session, hanya member dengan score (seq) di atasnya
+// yang diambil dan semua session live dikirim dalam satu pipeline
+func (mr *messageRepository) CountUnreadMessagesFromRedis(ctx context.Context, tx *gorm.DB, userID string, afters map[string]int64) (map[string]int64, error) {
+	res := make(map[string]int64, len(afters))
+	if len(afters) == 0 {
+		return res, nil
+	}
+
+	cmds := make(map[string]*redis.StringSliceCmd, len(afters))
+	pipe := mr.redis.Pipeline()
+	for sessionID, seq := range afters {
+		cmds[sessionID] = pipe.ZRangeByScore(ctx, fmt.Sprintf("session:%s:messages", sessionID), &redis.ZRangeBy{
+			Min: "(" + strconv.FormatInt(seq, 10),
+			Max: "+inf",
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get messages from redis: %w", err)
+	}
+
+	for sessionID, cmd := range cmds {
+		for _, raw := range cmd.Val() {
+			var evt dto.MessageEventPublish
+			if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+				continue
+			}
+			if evt.IsDeleted || evt.Sender.ID.String() == userID {
+				continue
+			}
+			res[sessionID]++
+		}
+	}
+
+	return res, nil
 }
 
 // UPDATE / PATCH
+func (mr *messageRepository) SetPersistCheckpoint(ctx context.Context, tx *gorm.DB, sessionID string, seq int64) error {
+	key := fmt.Sprintf("session:%s:checkpoint", sessionID)
+
+	// TTL disamakan dengan key messages
+	return mr.redis.Set(ctx, key, seq, 24*time.Hour).Err()
+}
+
+// RescoreLegacyMessages migrasi session live yang message-nya masih ber-score UnixNano (sebelum ada seq).
+// semua member diurutkan ulang (legacy sesuai waktu, lalu yang sudah ber-seq) dan diberi seq 1..n, urutan
+// yang sama dengan backfill seq di postgres. counter seq dinaikkan dan checkpoint persister di-reset.
+// return jumlah message yang di-score ulang, 0 kalau session tidak perlu dimigrasi
+func (mr *messageRepository) RescoreLegacyMessages(ctx context.Context, tx *gorm.DB, sessionID string) (int, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+	seqKey := fmt.Sprintf("session:%s:seq", sessionID)
+	checkpointKey := fmt.Sprintf("session:%s:checkpoint", sessionID)
+	idsKey := messageIDsKey(sessionID)
+
+	rescored := 0
+	migrate := func(rtx *redis.Tx) error {
+		rescored = 0
+
+		results, err := rtx.ZRangeWithScores(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		var legacy, current []redis.Z
+		for _, z := range results {
+			if z.Score >= legacyMessageScore {
+				legacy = append(legacy, z)
+				continue
+			}
+			current = append(current, z)
+		}
+		if len(legacy) == 0 {
+			return nil
+		}
+
+		counter, err := rtx.Get(ctx, seqKey).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		members := make([]redis.Z, 0, len(results))
+		index := make(map[string]interface{}, len(results))
+		for i, z := range append(legacy, current...) {
+			raw, ok := z.Member.(string)
+			if !ok {
+				continue
+			}
+
+			var evt dto.MessageEventPublish
+			if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+				mr.logger.Warn("failed to unmarshal redis message", zap.Error(err))
+				continue
+			}
+			evt.Seq = int64(i + 1)
+
+			data, err := json.Marshal(evt)
+			if err != nil {
+				return err
+			}
+			members = append(members, redis.Z{Score: float64(evt.Seq), Member: data})
+			index[evt.MessageID.String()] = evt.Seq
+		}
+		if counter < int64(len(members)) {
+			counter = int64(len(members))
+		}
+
+		ttl, err := rtx.TTL(ctx, key).Result()
+		if err != nil || ttl <= 0 {
+			ttl = 24 * time.Hour
+		}
+
+		_, err = rtx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key, checkpointKey, idsKey)
+			pipe.ZAdd(ctx, key, members...)
+			pipe.Expire(ctx, key, ttl)
+			if len(index) > 0 {
+				pipe.HSet(ctx, idsKey, index)
+				pipe.Expire(ctx, idsKey, ttl)
+			}
+			pipe.Set(ctx, seqKey, counter, ttl)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rescored = len(members)
+
+		return nil
+	}
+
+	// send yang masuk bersamaan mengubah key messages / seq, transaksi diulang dengan data terbaru
+	for i := 0; i < 5; i++ {
+		err := mr.redis.Watch(ctx, migrate, key, seqKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to rescore legacy messages: %w", err)
+		}
+
+		return rescored, nil
+	}
+
+	return 0, fmt.Errorf("failed to rescore legacy messages: %w", redis.TxFailedErr)
+}
+
+// ReplaceMessageInRedis compare-and-swap: member lama hanya diganti kalau masih sama persis dengan yang
+// dibaca caller. false berarti message sudah diubah request lain di antaranya, caller harus baca ulang
+func (mr *messageRepository) ReplaceMessageInRedis(ctx context.Context, tx *gorm.DB, sessionID string, old *redis.Z, message *dto.MessageEventPublish) (bool, error) {
+	key := fmt.Sprintf("session:%s:messages", sessionID)
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	replaced, err := replaceMemberScript.Run(ctx, mr.redis, []string{key}, old.Member, data).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to replace message in redis: %w", err)
+	}
+
+	return replaced == 1, nil
+}
+func (mr *messageRepository) UpdateMessage(ctx context.Context, tx *gorm.DB, message *entity.Message) error {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	// update lewat struct + Select supaya serializer json kolom mentions tetap dipakai
+	return tx.WithContext(ctx).
+		Model(&entity.Message{ID: message.ID}).
+		Select("text", "file_url", "mentions", "is_edited", "edited_at", "is_deleted", "updated_at").
+		Updates(message).Error
+}
 
 // DELETE / DELETE
+func (mr *messageRepository) DeleteMessagePin(ctx context.Context, tx *gorm.DB, sessionID, messageID string) (bool, error) {
+	if tx == nil {
+		tx = mr.db
+	}
+
+	// hard delete supaya message yang sama bisa di-pin ulang (unique index session + message)
+	res := tx.WithContext(ctx).
+		Unscoped().
+		Where("session_id = ? AND message_id = ?", sessionID, messageID).
+		Delete(&entity.MessagePin{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (mr *messageRepository) RemoveMessageFromRedis(ctx context.Context, tx *gorm.DB, sessionID string, messageID uuid.UUID, data []byte) error {
+	pipe := mr.redis.TxPipeline()
+	pipe.ZRem(ctx, fmt.Sprintf("session:%s:messages", sessionID), data)
+	pipe.HDel(ctx, messageIDsKey(sessionID), messageID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove message from redis: %w", err)
+	}
+
+	return nil
+}
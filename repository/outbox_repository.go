@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Amierza/chat-service/constants"
+	"github.com/Amierza/chat-service/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	IOutboxRepository interface {
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+
+		// CREATE / POST
+		CreateOutbox(ctx context.Context, tx *gorm.DB, outbox *entity.Outbox) error
+
+		// READ / GET
+		GetPendingOutboxes(ctx context.Context, tx *gorm.DB, limit int) ([]*entity.Outbox, error)
+
+		// UPDATE / PATCH
+		LeaseOutboxes(ctx context.Context, tx *gorm.DB, ids []string, until time.Time) error
+		MarkOutboxSent(ctx context.Context, tx *gorm.DB, id string) error
+		MarkOutboxFailed(ctx context.Context, tx *gorm.DB, id string, attempts int, nextAttemptAt time.Time, lastError string) error
+
+		// DELETE / DELETE
+	}
+
+	outboxRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewOutboxRepository(db *gorm.DB) *outboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (or *outboxRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return or.db.WithContext(ctx).Transaction(fn)
+}
+
+// CREATE / POST
+func (or *outboxRepository) CreateOutbox(ctx context.Context, tx *gorm.DB, outbox *entity.Outbox) error {
+	if tx == nil {
+		tx = or.db
+	}
+
+	return tx.WithContext(ctx).Create(&outbox).Error
+}
+
+// READ / GET
+func (or *outboxRepository) GetPendingOutboxes(ctx context.Context, tx *gorm.DB, limit int) ([]*entity.Outbox, error) {
+	if tx == nil {
+		tx = or.db
+	}
+
+	// SKIP LOCKED supaya beberapa replica relay tidak mengambil row yang sama
+	var outboxes []*entity.Outbox
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", constants.ENUM_OUTBOX_STATUS_PENDING, time.Now()).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&outboxes).Error; err != nil {
+		return nil, err
+	}
+
+	return outboxes, nil
+}
+
+// UPDATE / PATCH
+
+// LeaseOutboxes menunda next_attempt_at selama row sedang di-publish, jadi relay lain tidak mengambilnya
+// walaupun lock sudah dilepas. kalau relay mati di tengah jalan row otomatis diambil lagi setelah lease habis
+func (or *outboxRepository) LeaseOutboxes(ctx context.Context, tx *gorm.DB, ids []string, until time.Time) error {
+	if tx == nil {
+		tx = or.db
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).
+		Model(&entity.Outbox{}).
+		Where("id IN ?", ids).
+		Update("next_attempt_at", until).Error
+}
+func (or *outboxRepository) MarkOutboxSent(ctx context.Context, tx *gorm.DB, id string) error {
+	if tx == nil {
+		tx = or.db
+	}
+
+	now := time.Now()
+	return tx.WithContext(ctx).
+		Model(&entity.Outbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     constants.ENUM_OUTBOX_STATUS_SENT,
+			"sent_at":    &now,
+			"last_error": "",
+		}).Error
+}
+func (or *outboxRepository) MarkOutboxFailed(ctx context.Context, tx *gorm.DB, id string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	if tx == nil {
+		tx = or.db
+	}
+
+	return tx.WithContext(ctx).
+		Model(&entity.Outbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// DELETE / DELETE
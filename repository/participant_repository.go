@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	IParticipantRepository interface {
+		// CREATE / POST
+		SetSessionParticipants(ctx context.Context, sessionID string, userIDs []string, ttl time.Duration) error
+
+		// READ / GET
+		GetSessionParticipants(ctx context.Context, sessionID string) ([]string, error)
+
+		// UPDATE / PATCH
+
+		// DELETE / DELETE
+	}
+
+	participantRepository struct {
+		redis *redis.Client
+	}
+)
+
+func NewParticipantRepository(redis *redis.Client) *participantRepository {
+	return &participantRepository{
+		redis: redis,
+	}
+}
+
+func sessionParticipantsKey(sessionID string) string {
+	return fmt.Sprintf("session:%s:participants", sessionID)
+}
+
+// CREATE / POST
+func (pr *participantRepository) SetSessionParticipants(ctx context.Context, sessionID string, userIDs []string, ttl time.Duration) error {
+	key := sessionParticipantsKey(sessionID)
+
+	members := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		members = append(members, id)
+	}
+
+	pipe := pr.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(members) > 0 {
+		pipe.SAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache session participants: %w", err)
+	}
+
+	return nil
+}
+
+// READ / GET
+// GetSessionParticipants return slice kosong kalau cache belum ada
+func (pr *participantRepository) GetSessionParticipants(ctx context.Context, sessionID string) ([]string, error) {
+	userIDs, err := pr.redis.SMembers(ctx, sessionParticipantsKey(sessionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session participants: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const presenceLastSeenTTL = 30 * 24 * time.Hour
+
+type (
+	IPresenceRepository interface {
+		// CREATE / POST
+		AddConnection(ctx context.Context, userID, connID string, ttl time.Duration) (bool, error)
+
+		// READ / GET
+		GetPresence(ctx context.Context, userID string) (bool, *time.Time, error)
+
+		// UPDATE / PATCH
+		RefreshConnection(ctx context.Context, userID, connID string, ttl time.Duration) error
+
+		// DELETE / DELETE
+		RemoveConnection(ctx context.Context, userID, connID string) (bool, error)
+	}
+
+	presenceRepository struct {
+		redis *redis.Client
+	}
+)
+
+func NewPresenceRepository(redis *redis.Client) *presenceRepository {
+	return &presenceRepository{
+		redis: redis,
+	}
+}
+
+// setiap koneksi disimpan di sorted set dengan score = waktu expired, jadi koneksi dari instance
+// yang mati otomatis dianggap offline setelah heartbeat-nya berhenti
+func presenceConnsKey(userID string) string {
+	return fmt.Sprintf("presence:%s:conns", userID)
+}
+func presenceLastSeenKey(userID string) string {
+	return fmt.Sprintf("presence:%s:last_seen", userID)
+}
+
+// CREATE / POST
+// AddConnection return true kalau sebelumnya user tidak punya koneksi aktif (baru online)
+func (pr *presenceRepository) AddConnection(ctx context.Context, userID, connID string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	key := presenceConnsKey(userID)
+
+	pipe := pr.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	before := pipe.ZCard(ctx, key)
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: connID})
+	pipe.Expire(ctx, key, ttl)
+	pipe.Set(ctx, presenceLastSeenKey(userID), now.UnixMilli(), presenceLastSeenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to add presence connection: %w", err)
+	}
+
+	return before.Val() == 0, nil
+}
+
+// READ / GET
+func (pr *presenceRepository) GetPresence(ctx context.Context, userID string) (bool, *time.Time, error) {
+	now := time.Now()
+
+	pipe := pr.redis.Pipeline()
+	active := pipe.ZCount(ctx, presenceConnsKey(userID), "("+strconv.FormatInt(now.UnixMilli(), 10), "+inf")
+	lastSeen := pipe.Get(ctx, presenceLastSeenKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, nil, fmt.Errorf("failed to get presence: %w", err)
+	}
+
+	var seenAt *time.Time
+	if ms, err := lastSeen.Int64(); err == nil {
+		t := time.UnixMilli(ms)
+		seenAt = &t
+	}
+
+	return active.Val() > 0, seenAt, nil
+}
+
+// UPDATE / PATCH
+func (pr *presenceRepository) RefreshConnection(ctx context.Context, userID, connID string, ttl time.Duration) error {
+	now := time.Now()
+	key := presenceConnsKey(userID)
+
+	pipe := pr.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: connID})
+	pipe.Expire(ctx, key, ttl)
+	pipe.Set(ctx, presenceLastSeenKey(userID), now.UnixMilli(), presenceLastSeenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to refresh presence connection: %w", err)
+	}
+
+	return nil
+}
+
+// DELETE / DELETE
+// RemoveConnection return true kalau koneksi ini adalah koneksi aktif terakhir user (jadi offline)
+func (pr *presenceRepository) RemoveConnection(ctx context.Context, userID, connID string) (bool, error) {
+	now := time.Now()
+	key := presenceConnsKey(userID)
+
+	pipe := pr.redis.TxPipeline()
+	pipe.ZRem(ctx, key, connID)
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	after := pipe.ZCard(ctx, key)
+	pipe.Set(ctx, presenceLastSeenKey(userID), now.UnixMilli(), presenceLastSeenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to remove presence connection: %w", err)
+	}
+
+	return after.Val() == 0, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refill dan ambil token dalam satu langkah atomic. waktu diambil dari TIME redis
+// supaya semua instance memakai jam yang sama. return {allowed, remaining, retry_after_ms, reset_ms}
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((cost - tokens) / rate)
+end
+
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+type (
+	IRateLimitRepository interface {
+		// CREATE / POST
+
+		// READ / GET
+
+		// UPDATE / PATCH
+		TakeToken(ctx context.Context, key string, rule dto.RateLimitRule) (*dto.RateLimitResult, error)
+
+		// DELETE / DELETE
+	}
+
+	rateLimitRepository struct {
+		redis *redis.Client
+	}
+)
+
+func NewRateLimitRepository(redis *redis.Client) *rateLimitRepository {
+	return &rateLimitRepository{
+		redis: redis,
+	}
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("ratelimit:%s", key)
+}
+
+// UPDATE / PATCH
+func (rr *rateLimitRepository) TakeToken(ctx context.Context, key string, rule dto.RateLimitRule) (*dto.RateLimitResult, error) {
+	// rate di script dalam token per milidetik
+	rate := rule.RefillPerSecond / 1000
+	res, err := tokenBucketScript.Run(ctx, rr.redis, []string{rateLimitKey(key)}, rule.Capacity, rate, 1).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(res) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+
+	return &dto.RateLimitResult{
+		Allowed:    res[0] == 1,
+		Limit:      rule.Capacity,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		ResetAfter: time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
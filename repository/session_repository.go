@@ -2,20 +2,26 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"math"
 	"strconv"
 
+	"github.com/Amierza/chat-service/constants"
 	"github.com/Amierza/chat-service/dto"
 	"github.com/Amierza/chat-service/entity"
 	"github.com/Amierza/chat-service/response"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type (
 	ISessionRepository interface {
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+
 		// CREATE / POST
 		CreateSession(ctx context.Context, tx *gorm.DB, session *entity.Session) error
+		CreateNote(ctx context.Context, tx *gorm.DB, note *entity.Note) error
 
 		// READ / GET
 		GetThesisByID(ctx context.Context, tx *gorm.DB, thesisID string) (*entity.Thesis, bool, error)
@@ -24,9 +30,13 @@ type (
 		GetAllSessionsByUserID(ctx context.Context, tx *gorm.DB, user *entity.User, filter dto.SessionFilterQuery) ([]*entity.Session, error)
 		GetAllSessionsByUserIDWithPagination(ctx context.Context, tx *gorm.DB, user *entity.User, pagination response.PaginationRequest, filter dto.SessionFilterQuery) (dto.SessionPaginationRepositoryResponse, error)
 		GetNoteSummaryBySessionID(ctx context.Context, tx *gorm.DB, sessionID string) (*entity.Note, bool, error)
+		GetAllSessionsByStatuses(ctx context.Context, tx *gorm.DB, statuses []string) ([]*entity.Session, error)
+		GetLiveSessionsByUser(ctx context.Context, tx *gorm.DB, user *entity.User) ([]*entity.Session, error)
+		IsSessionParticipant(ctx context.Context, tx *gorm.DB, sessionID, userID string) (bool, error)
 
 		// UPDATE / PATCH
 		UpdateSession(ctx context.Context, tx *gorm.DB, session *entity.Session) error
+		UpdateSessionStatus(ctx context.Context, tx *gorm.DB, sessionID string, from, to entity.SessionStatus) (bool, error)
 
 		// DELETE / DELETE
 	}
@@ -42,6 +52,10 @@ func NewSessionRepository(db *gorm.DB) *sessionRepository {
 	}
 }
 
+func (sr *sessionRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return sr.db.WithContext(ctx).Transaction(fn)
+}
+
 // CREATE / POST
 func (sr *sessionRepository) CreateSession(ctx context.Context, tx *gorm.DB, session *entity.Session) error {
 	if tx == nil {
@@ -50,6 +64,13 @@ func (sr *sessionRepository) CreateSession(ctx context.Context, tx *gorm.DB, ses
 
 	return tx.WithContext(ctx).Create(&session).Error
 }
+func (sr *sessionRepository) CreateNote(ctx context.Context, tx *gorm.DB, note *entity.Note) error {
+	if tx == nil {
+		tx = sr.db
+	}
+
+	return tx.WithContext(ctx).Omit(clause.Associations).Create(&note).Error
+}
 
 // READ / GET
 func (sr *sessionRepository) GetThesisByID(ctx context.Context, tx *gorm.DB, thesisID string) (*entity.Thesis, bool, error) {
@@ -113,15 +134,37 @@ func (sr *sessionRepository) GetActiveSessionBySessionID(ctx context.Context, tx
 		Preload("UserOwner.Lecturer.StudyProgram.Faculty").
 		Where("id = ?", sessionID).
 		Take(&session).Error
-	if err != nil {
-		return &entity.Session{}, false, err
-	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return &entity.Session{}, false, nil
 	}
+	if err != nil {
+		return &entity.Session{}, false, err
+	}
 
 	return session, true, nil
 }
+
+// GetLiveSessionsByUser session ongoing / processing_summary yang message-nya masih di redis
+func (sr *sessionRepository) GetLiveSessionsByUser(ctx context.Context, tx *gorm.DB, user *entity.User) ([]*entity.Session, error) {
+	if tx == nil {
+		tx = sr.db
+	}
+
+	var sessions []*entity.Session
+	err := tx.WithContext(ctx).
+		Model(&entity.Session{}).
+		Joins("JOIN theses ON theses.id = sessions.thesis_id").
+		Where("sessions.status IN ?", []string{constants.ENUM_SESSION_STATUS_ONGOING, constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY}).
+		Where("theses.student_id = ? OR sessions.thesis_id IN (?)", user.StudentID,
+			tx.Table("thesis_supervisors").Select("thesis_id").Where("lecturer_id = ? AND deleted_at IS NULL", user.LecturerID),
+		).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
 func (sr *sessionRepository) GetAllSessionsByUserID(ctx context.Context, tx *gorm.DB, user *entity.User, filter dto.SessionFilterQuery) ([]*entity.Session, error) {
 	if tx == nil {
 		tx = sr.db
@@ -290,17 +333,65 @@ func (sr *sessionRepository) GetNoteSummaryBySessionID(ctx context.Context, tx *
 		Preload("Session.UserOwner.Lecturer.StudyProgram.Faculty").
 		Where("session_id = ?", sessionID).
 		Take(&note).Error
-	if err != nil {
-		return &entity.Note{}, false, err
-	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return &entity.Note{}, false, nil
 	}
+	if err != nil {
+		return &entity.Note{}, false, err
+	}
 
 	return note, true, nil
 }
+func (sr *sessionRepository) GetAllSessionsByStatuses(ctx context.Context, tx *gorm.DB, statuses []string) ([]*entity.Session, error) {
+	if tx == nil {
+		tx = sr.db
+	}
+
+	var sessions []*entity.Session
+	if err := tx.WithContext(ctx).
+		Model(&entity.Session{}).
+		Where("status IN ?", statuses).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
 
 // UPDATE / PATCH
+// IsSessionParticipant cek langsung ke db apakah user mahasiswa pemilik thesis session atau salah satu
+// dosen pembimbingnya, jadi perubahan pembimbing langsung berlaku
+func (sr *sessionRepository) IsSessionParticipant(ctx context.Context, tx *gorm.DB, sessionID, userID string) (bool, error) {
+	if tx == nil {
+		tx = sr.db
+	}
+
+	var ok bool
+	err := tx.WithContext(ctx).Raw(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions s
+			JOIN theses t ON t.id = s.thesis_id AND t.deleted_at IS NULL
+			JOIN users u ON u.id = @user_id AND u.deleted_at IS NULL
+			WHERE s.id = @session_id AND s.deleted_at IS NULL
+				AND (
+					t.student_id = u.student_id
+					OR EXISTS (
+						SELECT 1 FROM thesis_supervisors ts
+						WHERE ts.thesis_id = t.id AND ts.lecturer_id = u.lecturer_id AND ts.deleted_at IS NULL
+					)
+				)
+		)`,
+		sql.Named("session_id", sessionID),
+		sql.Named("user_id", userID),
+	).Scan(&ok).Error
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
 func (sr *sessionRepository) UpdateSession(ctx context.Context, tx *gorm.DB, session *entity.Session) error {
 	if tx == nil {
 		tx = sr.db
@@ -308,3 +399,19 @@ func (sr *sessionRepository) UpdateSession(ctx context.Context, tx *gorm.DB, ses
 
 	return tx.WithContext(ctx).Where("id = ?", session.ID).Updates(&session).Error
 }
+func (sr *sessionRepository) UpdateSessionStatus(ctx context.Context, tx *gorm.DB, sessionID string, from, to entity.SessionStatus) (bool, error) {
+	if tx == nil {
+		tx = sr.db
+	}
+
+	// hanya pindah status kalau status sekarang masih sesuai, jadi aman dari proses ganda
+	result := tx.WithContext(ctx).
+		Model(&entity.Session{}).
+		Where("id = ? AND status = ?", sessionID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	IStreamTicketRepository interface {
+		// CREATE / POST
+		CreateStreamTicket(ctx context.Context, ticket, token string, ttl time.Duration) error
+
+		// READ / GET
+
+		// UPDATE / PATCH
+
+		// DELETE / DELETE
+		ConsumeStreamTicket(ctx context.Context, ticket string) (string, bool, error)
+	}
+
+	streamTicketRepository struct {
+		redis *redis.Client
+	}
+)
+
+func NewStreamTicketRepository(redis *redis.Client) *streamTicketRepository {
+	return &streamTicketRepository{
+		redis: redis,
+	}
+}
+
+func streamTicketKey(ticket string) string {
+	return fmt.Sprintf("sse:ticket:%s", ticket)
+}
+
+// CREATE / POST
+func (sr *streamTicketRepository) CreateStreamTicket(ctx context.Context, ticket, token string, ttl time.Duration) error {
+	if err := sr.redis.Set(ctx, streamTicketKey(ticket), token, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to create stream ticket: %w", err)
+	}
+
+	return nil
+}
+
+// DELETE / DELETE
+// ConsumeStreamTicket ticket hanya bisa dipakai sekali, GETDEL mengambil token sekaligus menghapus ticket
+func (sr *streamTicketRepository) ConsumeStreamTicket(ctx context.Context, ticket string) (string, bool, error) {
+	token, err := sr.redis.GetDel(ctx, streamTicketKey(ticket)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to consume stream ticket: %w", err)
+	}
+
+	return token, true, nil
+}
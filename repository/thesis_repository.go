@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"math"
 
@@ -18,6 +19,7 @@ type (
 		// READ / GET
 		GetThesisByID(ctx context.Context, tx *gorm.DB, id string) (*entity.Thesis, bool, error)
 		GetAllThesesByLecturerIDWithPagination(ctx context.Context, tx *gorm.DB, pagination response.PaginationRequest, lecturerID string) (dto.ThesisPaginationRepositoryResponse, error)
+		GetRelatedUserIDsByUser(ctx context.Context, tx *gorm.DB, user *entity.User) ([]string, error)
 
 		// UPDATE / PATCH
 		UpdateThesis(ctx context.Context, tx *gorm.DB, thesis *entity.Thesis) error
@@ -114,6 +116,40 @@ func (tr *thesisRepository) GetAllThesesByLecturerIDWithPagination(ctx context.C
 	}, err
 }
 
+func (tr *thesisRepository) GetRelatedUserIDsByUser(ctx context.Context, tx *gorm.DB, user *entity.User) ([]string, error) {
+	if tx == nil {
+		tx = tr.db
+	}
+
+	// semua user (mahasiswa & dosen pembimbing) yang satu thesis dengan user
+	var userIDs []string
+	err := tx.WithContext(ctx).Raw(`
+		WITH related_theses AS (
+			SELECT t.id, t.student_id FROM theses t
+			WHERE t.deleted_at IS NULL AND (
+				t.student_id = @student_id
+				OR t.id IN (SELECT ts.thesis_id FROM thesis_supervisors ts WHERE ts.lecturer_id = @lecturer_id AND ts.deleted_at IS NULL)
+			)
+		)
+		SELECT DISTINCT u.id FROM users u
+		WHERE u.deleted_at IS NULL AND u.id <> @user_id AND (
+			u.student_id IN (SELECT student_id FROM related_theses)
+			OR u.lecturer_id IN (
+				SELECT ts.lecturer_id FROM thesis_supervisors ts
+				WHERE ts.thesis_id IN (SELECT id FROM related_theses) AND ts.deleted_at IS NULL
+			)
+		)`,
+		sql.Named("student_id", user.StudentID),
+		sql.Named("lecturer_id", user.LecturerID),
+		sql.Named("user_id", user.ID),
+	).Scan(&userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
 // UPDATE / PATCH
 func (sr *thesisRepository) UpdateThesis(ctx context.Context, tx *gorm.DB, thesis *entity.Thesis) error {
 	if tx == nil {
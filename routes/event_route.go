@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/Amierza/chat-service/handler"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/middleware"
+	"github.com/Amierza/chat-service/service"
+	"github.com/gin-gonic/gin"
+)
+
+func Event(route *gin.Engine, eventHandler handler.IEventHandler, jwt jwt.IJWT, streamTicketService service.IStreamTicketService) {
+	routes := route.Group("/api/v1/events")
+	{
+		routes.POST("/ticket", middleware.Authentication(jwt), eventHandler.CreateTicket)
+		routes.GET("/stream", middleware.StreamAuthentication(jwt, streamTicketService), eventHandler.Stream)
+	}
+}
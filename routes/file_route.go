@@ -4,12 +4,13 @@ import (
 	"github.com/Amierza/chat-service/handler"
 	"github.com/Amierza/chat-service/jwt"
 	"github.com/Amierza/chat-service/middleware"
+	"github.com/Amierza/chat-service/service"
 	"github.com/gin-gonic/gin"
 )
 
-func File(route *gin.Engine, fileHandler handler.IFileHandler, jwt jwt.IJWT) {
+func File(route *gin.Engine, fileHandler handler.IFileHandler, jwt jwt.IJWT, rateLimitService service.IRateLimitService) {
 	routes := route.Group("/api/v1/uploads").Use(middleware.Authentication(jwt))
 	{
-		routes.POST("", fileHandler.UploadFiles)
+		routes.POST("", middleware.RateLimit(rateLimitService, jwt, service.RateLimitUpload), fileHandler.UploadFiles)
 	}
 }
@@ -4,13 +4,21 @@ import (
 	"github.com/Amierza/chat-service/handler"
 	"github.com/Amierza/chat-service/jwt"
 	"github.com/Amierza/chat-service/middleware"
+	"github.com/Amierza/chat-service/service"
 	"github.com/gin-gonic/gin"
 )
 
-func Message(route *gin.Engine, messageHandler handler.IMessageHandler, jwt jwt.IJWT) {
+func Message(route *gin.Engine, messageHandler handler.IMessageHandler, jwt jwt.IJWT, rateLimitService service.IRateLimitService) {
 	routes := route.Group("/api/v1/sessions/:session_id/messages").Use(middleware.Authentication(jwt))
 	{
-		routes.POST("", messageHandler.Send)
+		routes.POST("", middleware.RateLimit(rateLimitService, jwt, service.RateLimitMessageSend), messageHandler.Send)
 		routes.GET("", messageHandler.List)
+		routes.POST("/read", messageHandler.MarkRead)
+		routes.GET("/pinned", messageHandler.Pinned)
+		routes.GET("/:id/replies", messageHandler.Replies)
+		routes.PATCH("/:id", messageHandler.Edit)
+		routes.DELETE("/:id", messageHandler.Delete)
+		routes.POST("/:id/pin", messageHandler.Pin)
+		routes.DELETE("/:id/pin", messageHandler.Unpin)
 	}
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Amierza/chat-service/handler"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func Presence(route *gin.Engine, presenceHandler handler.IPresenceHandler, jwt jwt.IJWT) {
+	routes := route.Group("/api/v1/theses").Use(middleware.Authentication(jwt))
+	{
+		routes.GET("/:id/presence", presenceHandler.GetThesisPresence)
+	}
+}
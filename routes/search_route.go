@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Amierza/chat-service/handler"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func Search(route *gin.Engine, searchHandler handler.ISearchHandler, jwt jwt.IJWT) {
+	routes := route.Group("/api/v1/search").Use(middleware.Authentication(jwt))
+	{
+		routes.GET("/messages", searchHandler.SearchMessages)
+	}
+}
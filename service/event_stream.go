@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleEventStream fallback Server-Sent Events untuk jaringan yang memblokir upgrade websocket.
+// koneksi sse didaftarkan di registry yang sama dengan websocket, jadi SendToUser / BroadcastToRoom
+// tidak perlu tahu transport yang dipakai user. ctx harus sudah lewat middleware StreamAuthentication
+func (wh *webSocketService) HandleEventStream(ctx *gin.Context, rooms []string) {
+	userID := ctx.GetString("user_id")
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // matikan buffering di reverse proxy (nginx)
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	// conn nil: tidak ada writePump, queue send dikuras oleh loop di bawah
+	token := ctx.GetString("Authorization")
+	client := newWSClient(userID, token, nil, wh.metrics)
+	defer wh.unregister(client, 0)
+
+	devices := wh.register(client, rooms)
+	log.Printf("User %v connected via SSE (%d device)", userID, devices)
+
+	// resume sesuai spesifikasi EventSource, query param untuk client yang tidak bisa set header
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+	if id, err := strconv.ParseInt(lastEventID, 10, 64); err == nil {
+		// replay ditulis langsung supaya log yang panjang tidak memenuhi queue send
+		entries, err := wh.eventLogRepo.GetEventsAfter(context.Background(), userID, id)
+		if err != nil {
+			log.Printf("[SSE] failed to replay events for %s: %v", userID, err)
+		}
+		for _, entry := range entries {
+			if err := writeSSE(ctx, strconv.FormatInt(entry.ID, 10), withEventID(entry.ID, entry.Data)); err != nil {
+				return
+			}
+		}
+		log.Printf("[SSE] replayed %d event to %s after %d", len(entries), userID, id)
+	}
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	// sse tidak punya close code, kirim event token_expired lalu tutup stream
+	expiresAt, err := wh.jwt.GetExpiryByToken(token)
+	if err != nil {
+		return
+	}
+	expired := time.NewTimer(time.Until(expiresAt))
+	defer expired.Stop()
+
+	for {
+		select {
+		case message := <-client.send:
+			// id sse diambil dari event_id, kosong untuk event ephemeral
+			var event struct {
+				EventID int64 `json:"event_id"`
+			}
+			if err := json.Unmarshal(message, &event); err != nil {
+				continue
+			}
+			id := ""
+			if event.EventID > 0 {
+				id = strconv.FormatInt(event.EventID, 10)
+			}
+			if err := writeSSE(ctx, id, message); err != nil {
+				wh.metrics.writeErrors.Add(1)
+				log.Printf("[SSE] write error to %s: %v", userID, err)
+				return
+			}
+			wh.metrics.messagesSent.Add(1)
+		case <-ticker.C:
+			// comment line sebagai keepalive, sekaligus heartbeat presence
+			if _, err := fmt.Fprint(ctx.Writer, ": ping\n\n"); err != nil {
+				wh.metrics.writeErrors.Add(1)
+				return
+			}
+			ctx.Writer.Flush()
+			wh.notify(func(l WSConnectionListener) { l.Heartbeat(userID, client.id) })
+		case <-expired.C:
+			writeSSE(ctx, "", []byte(`{"event":"token_expired"}`))
+			return
+		case <-client.done:
+			// contoh: slow consumer
+			return
+		case <-ctx.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE menulis satu event, payload sama persis dengan yang dikirim lewat websocket
+func writeSSE(ctx *gin.Context, id string, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(ctx.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(ctx.Writer, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	ctx.Writer.Flush()
+
+	return nil
+}
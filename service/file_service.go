@@ -2,39 +2,78 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type (
 	IFileService interface {
 		// public function
-		UploadFiles(ctx context.Context, files []*multipart.FileHeader) ([]string, error)
+		UploadFiles(ctx context.Context, files []*multipart.FileHeader) ([]dto.AttachmentResponse, error)
 		// private / helper function
-		saveUploadedFile(file *multipart.FileHeader, savePath string) error
+		saveUploadedFile(file *multipart.FileHeader, savePath string) (*savedFile, error)
 		createFile(path string) (*os.File, error)
-		copyFile(dst *os.File, src multipart.File) (int64, error)
+		copyFile(dst io.Writer, src multipart.File) (int64, error)
 	}
 
 	fileService struct {
+		attachmentRepo repository.IAttachmentRepository
+		logger         *zap.Logger
+		jwt            jwt.IJWT
+	}
+
+	// savedFile hasil hitung saat file disalin, supaya file cukup dibaca sekali
+	savedFile struct {
+		size     int64
+		checksum string
+		mimeType string
 	}
 )
 
-func NewFileService() *fileService {
-	return &fileService{}
+func NewFileService(attachmentRepo repository.IAttachmentRepository, logger *zap.Logger, jwt jwt.IJWT) *fileService {
+	return &fileService{
+		attachmentRepo: attachmentRepo,
+		logger:         logger,
+		jwt:            jwt,
+	}
 }
 
-func (fs *fileService) UploadFiles(ctx context.Context, files []*multipart.FileHeader) ([]string, error) {
+func (fs *fileService) UploadFiles(ctx context.Context, files []*multipart.FileHeader) ([]dto.AttachmentResponse, error) {
 	if len(files) == 0 {
 		return nil, dto.ErrNoFilesUploaded
 	}
 
-	var uploadedPaths []string
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := fs.jwt.GetUserIDByToken(token)
+	if err != nil {
+		fs.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	uploaderID, err := uuid.Parse(userIDString)
+	if err != nil {
+		return nil, dto.ErrParseStringToUUID
+	}
+
+	var attachments []entity.Attachment
+	var savedPaths []string
 	for _, file := range files {
 		// Validasi ekstensi
 		ext := strings.ToLower(filepath.Ext(file.Filename))
@@ -56,45 +95,100 @@ func (fs *fileService) UploadFiles(ctx context.Context, files []*multipart.FileH
 			".pptx": true,
 		}
 		if !allowedExt[ext] {
+			fs.removeFiles(savedPaths)
 			return nil, dto.ErrInvalidFileType
 		}
 
-		// Gunakan nama file asli dari user
-		// (bisa ditambahkan sanitasi untuk keamanan)
-		fileName := filepath.Base(file.Filename)
-		savePath := filepath.Join("uploads/", fileName)
+		// nama file di disk memakai id attachment supaya upload dengan nama sama tidak saling menimpa,
+		// nama asli disimpan di metadata
+		id := uuid.New()
+		savePath := filepath.Join("uploads/", id.String()+ext)
 
 		// Pastikan folder ada
 		if err := os.MkdirAll(filepath.Dir(savePath), os.ModePerm); err != nil {
+			fs.removeFiles(savedPaths)
 			return nil, dto.ErrCreateFolderAssets
 		}
 
 		// Simpan file
-		if err := fs.saveUploadedFile(file, savePath); err != nil {
+		saved, err := fs.saveUploadedFile(file, savePath)
+		if err != nil {
+			fs.logger.Error("failed to save uploaded file",
+				zap.String("file_name", file.Filename),
+				zap.Error(err),
+			)
+			fs.removeFiles(append(savedPaths, savePath))
 			return nil, dto.ErrSaveFile
 		}
+		savedPaths = append(savedPaths, savePath)
+
+		attachments = append(attachments, entity.Attachment{
+			ID:           id,
+			OriginalName: filepath.Base(file.Filename),
+			Path:         filepath.ToSlash(savePath),
+			Size:         saved.size,
+			MimeType:     saved.mimeType,
+			Checksum:     saved.checksum,
+			UploaderID:   uploaderID,
+		})
+	}
 
-		uploadedPaths = append(uploadedPaths, savePath)
+	if err := fs.attachmentRepo.CreateAttachments(ctx, nil, attachments); err != nil {
+		fs.logger.Error("failed to create attachments",
+			zap.String("uploader_id", userIDString),
+			zap.Error(err),
+		)
+		fs.removeFiles(savedPaths)
+		return nil, dto.ErrCreateAttachment
+	}
+	fs.logger.Info("success upload files",
+		zap.String("uploader_id", userIDString),
+		zap.Int("count", len(attachments)),
+	)
+
+	res := make([]dto.AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		res = append(res, toAttachmentResponse(attachment))
 	}
 
-	return uploadedPaths, nil
+	return res, nil
 }
 
-func (fs *fileService) saveUploadedFile(file *multipart.FileHeader, savePath string) error {
+func (fs *fileService) saveUploadedFile(file *multipart.FileHeader, savePath string) (*savedFile, error) {
 	src, err := file.Open()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer src.Close()
 
+	// mime dideteksi dari isi file, bukan dari header Content-Type yang dikirim client
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	mimeType := http.DetectContentType(head[:n])
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
 	dst, err := fs.createFile(savePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer dst.Close()
 
-	_, err = fs.copyFile(dst, src)
-	return err
+	hash := sha256.New()
+	size, err := fs.copyFile(io.MultiWriter(dst, hash), src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &savedFile{
+		size:     size,
+		checksum: hex.EncodeToString(hash.Sum(nil)),
+		mimeType: mimeType,
+	}, nil
 }
 
 // ini nanti kita ganti kalau mau langsung ke S3
@@ -102,6 +196,31 @@ func (fs *fileService) createFile(path string) (*os.File, error) {
 	return os.Create(path)
 }
 
-func (fs *fileService) copyFile(dst *os.File, src multipart.File) (int64, error) {
+func (fs *fileService) copyFile(dst io.Writer, src multipart.File) (int64, error) {
 	return io.Copy(dst, src)
 }
+
+// removeFiles membersihkan file yang sudah tersimpan kalau upload gagal di tengah jalan
+func (fs *fileService) removeFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fs.logger.Warn("failed to remove uploaded file",
+				zap.String("path", path),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func toAttachmentResponse(attachment entity.Attachment) dto.AttachmentResponse {
+	return dto.AttachmentResponse{
+		ID:           attachment.ID,
+		OriginalName: attachment.OriginalName,
+		URL:          "/" + attachment.Path,
+		Size:         attachment.Size,
+		MimeType:     attachment.MimeType,
+		Checksum:     attachment.Checksum,
+		UploaderID:   attachment.UploaderID,
+		CreatedAt:    attachment.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/Amierza/chat-service/constants"
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const defaultMessagePersistInterval = 30 * time.Second
+
+type (
+	IMessagePersisterService interface {
+		Run(ctx context.Context)
+		Checkpoint(ctx context.Context, sessionID string) (int, error)
+		Flush(ctx context.Context, sessionID string) (int, error)
+	}
+
+	messagePersisterService struct {
+		messageRepo repository.IMessageRepository
+		sessionRepo repository.ISessionRepository
+		logger      *zap.Logger
+		interval    time.Duration
+	}
+)
+
+func NewMessagePersisterService(messageRepo repository.IMessageRepository, sessionRepo repository.ISessionRepository, logger *zap.Logger) *messagePersisterService {
+	interval := defaultMessagePersistInterval
+	if raw := os.Getenv("MESSAGE_PERSIST_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	return &messagePersisterService{
+		messageRepo: messageRepo,
+		sessionRepo: sessionRepo,
+		logger:      logger,
+		interval:    interval,
+	}
+}
+
+// Run menyalin chat live dari redis ke postgres secara berkala sampai ctx selesai
+func (mps *messagePersisterService) Run(ctx context.Context) {
+	ticker := time.NewTicker(mps.interval)
+	defer ticker.Stop()
+
+	mps.logger.Info("message persister started",
+		zap.Duration("interval", mps.interval),
+	)
+	mps.rescoreLegacySessions(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			mps.logger.Info("message persister stopped")
+			return
+		case <-ticker.C:
+			mps.checkpointActiveSessions(ctx)
+		}
+	}
+}
+
+// rescoreLegacySessions sekali saat start: session live dari sebelum ada seq masih ber-score UnixNano,
+// diurutkan ulang supaya cursor, sync_messages dan checkpoint berbasis seq tetap benar
+func (mps *messagePersisterService) rescoreLegacySessions(ctx context.Context) {
+	sessions, err := mps.activeSessions(ctx)
+	if err != nil {
+		mps.logger.Error("failed to get active sessions for rescore",
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, session := range sessions {
+		count, err := mps.messageRepo.RescoreLegacyMessages(ctx, nil, session.ID.String())
+		if err != nil {
+			mps.logger.Error("failed to rescore legacy session messages",
+				zap.String("session_id", session.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if count > 0 {
+			mps.logger.Info("success rescore legacy session messages",
+				zap.String("session_id", session.ID.String()),
+				zap.Int("count", count),
+			)
+		}
+	}
+}
+
+func (mps *messagePersisterService) activeSessions(ctx context.Context) ([]*entity.Session, error) {
+	// processing_summary ikut dicek supaya flush yang gagal saat End tetap tersimpan
+	return mps.sessionRepo.GetAllSessionsByStatuses(ctx, nil, []string{
+		constants.ENUM_SESSION_STATUS_ONGOING,
+		constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY,
+	})
+}
+
+func (mps *messagePersisterService) checkpointActiveSessions(ctx context.Context) {
+	sessions, err := mps.activeSessions(ctx)
+	if err != nil {
+		mps.logger.Error("failed to get active sessions for checkpoint",
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, session := range sessions {
+		if _, err := mps.Checkpoint(ctx, session.ID.String()); err != nil {
+			mps.logger.Error("failed to checkpoint session messages",
+				zap.String("session_id", session.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Checkpoint hanya menyimpan message yang masuk setelah checkpoint terakhir
+func (mps *messagePersisterService) Checkpoint(ctx context.Context, sessionID string) (int, error) {
+	lastSeq, err := mps.messageRepo.GetPersistCheckpoint(ctx, nil, sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	return mps.persistAfter(ctx, sessionID, lastSeq)
+}
+
+// Flush menyimpan seluruh message di redis, dipanggil saat session diakhiri
+func (mps *messagePersisterService) Flush(ctx context.Context, sessionID string) (int, error) {
+	return mps.persistAfter(ctx, sessionID, 0)
+}
+
+func (mps *messagePersisterService) persistAfter(ctx context.Context, sessionID string, seq int64) (int, error) {
+	events, lastSeq, err := mps.messageRepo.GetMessageFromRedisAfterSeq(ctx, nil, sessionID, seq)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	messages := make([]entity.Message, 0, len(events))
+	seqs := make([]int64, 0, len(events))
+	for _, evt := range events {
+		messages = append(messages, mps.toEntity(evt))
+		seqs = append(seqs, evt.Seq)
+	}
+
+	// upsert hanya bisa menangani konflik id, seq yang sudah dipegang message lain (entry redis basi / replay)
+	// disaring dulu supaya unique index tidak menggagalkan seluruh batch di setiap flush
+	owners, err := mps.messageRepo.GetMessageIDsBySeqs(ctx, nil, sessionID, seqs)
+	if err != nil {
+		return 0, err
+	}
+	messages, dropped := dropSeqConflicts(messages, owners)
+	for _, message := range dropped {
+		mps.logger.Warn("skip persisting message with conflicting seq",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", message.ID.String()),
+			zap.Int64("seq", message.Seq),
+		)
+	}
+
+	if err := mps.messageRepo.UpsertMessages(ctx, nil, messages); err != nil {
+		return 0, err
+	}
+
+	if err := mps.messageRepo.SetPersistCheckpoint(ctx, nil, sessionID, lastSeq); err != nil {
+		// checkpoint gagal cuma bikin message disalin ulang, upsert tetap idempotent
+		mps.logger.Warn("failed to save persist checkpoint",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+	}
+
+	mps.logger.Info("success persist messages from redis",
+		zap.String("session_id", sessionID),
+		zap.Int("count", len(messages)),
+	)
+
+	return len(messages), nil
+}
+
+// dropSeqConflicts buang message yang seq-nya sudah dipakai id lain, baik di postgres (owners) maupun
+// message sebelumnya di batch yang sama
+func dropSeqConflicts(messages []entity.Message, owners map[int64]uuid.UUID) ([]entity.Message, []entity.Message) {
+	kept := make([]entity.Message, 0, len(messages))
+	var dropped []entity.Message
+
+	seen := make(map[int64]uuid.UUID, len(messages))
+	for _, message := range messages {
+		if owner, ok := owners[message.Seq]; ok && owner != message.ID {
+			dropped = append(dropped, message)
+			continue
+		}
+		if owner, ok := seen[message.Seq]; ok && owner != message.ID {
+			dropped = append(dropped, message)
+			continue
+		}
+		seen[message.Seq] = message.ID
+		kept = append(kept, message)
+	}
+
+	return kept, dropped
+}
+
+func (mps *messagePersisterService) toEntity(evt dto.MessageEventPublish) entity.Message {
+	createdAt, err := time.Parse(time.RFC3339Nano, evt.Timestamp)
+	if err != nil {
+		mps.logger.Warn("failed to parse message timestamp",
+			zap.String("message_id", evt.MessageID.String()),
+			zap.String("timestamp", evt.Timestamp),
+			zap.Error(err),
+		)
+		createdAt = time.Now()
+	}
+
+	message := entity.Message{
+		ID:              evt.MessageID,
+		Seq:             evt.Seq,
+		Text:            evt.Text,
+		FileURL:         evt.FileURL,
+		SenderRole:      entity.Role(evt.Sender.Role),
+		SenderID:        evt.Sender.ID,
+		SessionID:       evt.SessionID,
+		ParentMessageID: evt.ParentMessageID,
+		ClientMessageID: evt.ClientMessageID,
+		Mentions:        evt.Mentions,
+		IsEdited:        evt.IsEdited,
+		IsDeleted:       evt.IsDeleted,
+		TimeStamp: entity.TimeStamp{
+			CreatedAt: createdAt,
+			UpdatedAt: time.Now(),
+		},
+	}
+	if evt.IsText != nil {
+		message.IsText = *evt.IsText
+	}
+	if evt.EditedAt != "" {
+		if editedAt, err := time.Parse(time.RFC3339Nano, evt.EditedAt); err == nil {
+			message.EditedAt = &editedAt
+		}
+	}
+
+	return message
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Amierza/chat-service/entity"
+	"github.com/google/uuid"
+)
+
+func TestDropSeqConflicts(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		messages    []entity.Message
+		owners      map[int64]uuid.UUID
+		wantKept    []uuid.UUID
+		wantDropped []uuid.UUID
+	}{
+		{
+			name:     "no conflict",
+			messages: []entity.Message{{ID: a, Seq: 1}, {ID: b, Seq: 2}},
+			owners:   map[int64]uuid.UUID{},
+			wantKept: []uuid.UUID{a, b},
+		},
+		{
+			name:     "same id already persisted is an update",
+			messages: []entity.Message{{ID: a, Seq: 1}},
+			owners:   map[int64]uuid.UUID{1: a},
+			wantKept: []uuid.UUID{a},
+		},
+		{
+			name:        "seq held by another persisted message",
+			messages:    []entity.Message{{ID: a, Seq: 1}, {ID: b, Seq: 2}},
+			owners:      map[int64]uuid.UUID{1: c},
+			wantKept:    []uuid.UUID{b},
+			wantDropped: []uuid.UUID{a},
+		},
+		{
+			name:        "duplicate seq inside the batch keeps the first",
+			messages:    []entity.Message{{ID: a, Seq: 3}, {ID: b, Seq: 3}, {ID: c, Seq: 4}},
+			owners:      map[int64]uuid.UUID{},
+			wantKept:    []uuid.UUID{a, c},
+			wantDropped: []uuid.UUID{b},
+		},
+		{
+			name:        "every message conflicts",
+			messages:    []entity.Message{{ID: a, Seq: 1}},
+			owners:      map[int64]uuid.UUID{1: b},
+			wantDropped: []uuid.UUID{a},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := dropSeqConflicts(tt.messages, tt.owners)
+			assertMessageIDs(t, "kept", kept, tt.wantKept)
+			assertMessageIDs(t, "dropped", dropped, tt.wantDropped)
+		})
+	}
+}
+
+func assertMessageIDs(t *testing.T, label string, got []entity.Message, want []uuid.UUID) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d messages, want %d", label, len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Errorf("%s[%d]: got %s, want %s", label, i, got[i].ID, want[i])
+		}
+	}
+}
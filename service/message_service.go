@@ -3,23 +3,36 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Amierza/chat-service/constants"
 	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/helper"
 	"github.com/Amierza/chat-service/jwt"
 	"github.com/Amierza/chat-service/repository"
-	"github.com/Amierza/chat-service/response"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type (
 	IMessageService interface {
-		Send(ctx context.Context, req dto.SendMessageRequest, sessionID string) error
-		List(ctx context.Context, req response.PaginationRequest, sessionID string) (*dto.MessagePaginationResponse, error)
+		Send(ctx context.Context, req dto.SendMessageRequest, sessionID string) (*dto.MessageResponse, error)
+		List(ctx context.Context, req dto.MessageCursorRequest, sessionID string) (*dto.MessagePaginationResponse, error)
+		Replies(ctx context.Context, req dto.MessageCursorRequest, sessionID, messageID string) (*dto.MessagePaginationResponse, error)
+		Edit(ctx context.Context, req dto.EditMessageRequest, sessionID, messageID string) (*dto.MessageResponse, error)
+		Delete(ctx context.Context, sessionID, messageID string) (*dto.MessageResponse, error)
+		MarkRead(ctx context.Context, req dto.MarkReadRequest, sessionID string) (*dto.ReadCursorResponse, error)
+		Typing(ctx context.Context, sessionID string, isTyping bool) error
+		Pin(ctx context.Context, sessionID, messageID string) (*dto.PinnedMessageResponse, error)
+		Unpin(ctx context.Context, sessionID, messageID string) (*dto.PinnedMessageResponse, error)
+		Pinned(ctx context.Context, sessionID string) ([]dto.PinnedMessageResponse, error)
 	}
 
 	messageService struct {
@@ -28,12 +41,36 @@ type (
 		userRepo    repository.IUserRepository
 		logger      *zap.Logger
 		wsService   IWebsocketService
-		jwt         jwt.IJWT
-		redis       *redis.Client
+
+		notificationRepo repository.INotificationRepository
+		attachmentRepo   repository.IAttachmentRepository
+		jwt              jwt.IJWT
+		redis            *redis.Client
+
+		participantService IParticipantService
+		rateLimitService   IRateLimitService
+
+		// timer typing_stopped otomatis per session + user di instance ini
+		typingTimers map[string]*time.Timer
+		typingMu     sync.Mutex
 	}
 )
 
-func NewMessageService(messageRepo repository.IMessageRepository, sessionRepo repository.ISessionRepository, userRepo repository.IUserRepository, logger *zap.Logger, wsService IWebsocketService, jwt jwt.IJWT, redis *redis.Client) *messageService {
+const (
+	messageDefaultLimit = 20
+	messageMaxLimit     = 100
+
+	messageMaxAttachments = 10
+)
+
+// idempotencyWindow lama client message id diingat di redis, sama dengan TTL key messages.
+// setelah lewat, retry masih dicek ke postgres lewat id message yang deterministik
+const idempotencyWindow = 24 * time.Hour
+
+// typingTTL batas typing tanpa refresh dari client sebelum server mengirim typing_stopped
+const typingTTL = 6 * time.Second
+
+func NewMessageService(messageRepo repository.IMessageRepository, sessionRepo repository.ISessionRepository, userRepo repository.IUserRepository, logger *zap.Logger, wsService IWebsocketService, jwt jwt.IJWT, redis *redis.Client, participantService IParticipantService, notificationRepo repository.INotificationRepository, attachmentRepo repository.IAttachmentRepository, rateLimitService IRateLimitService) *messageService {
 	return &messageService{
 		messageRepo: messageRepo,
 		sessionRepo: sessionRepo,
@@ -42,10 +79,17 @@ func NewMessageService(messageRepo repository.IMessageRepository, sessionRepo re
 		wsService:   wsService,
 		jwt:         jwt,
 		redis:       redis,
+
+		participantService: participantService,
+		notificationRepo:   notificationRepo,
+		attachmentRepo:     attachmentRepo,
+		rateLimitService:   rateLimitService,
+
+		typingTimers: make(map[string]*time.Timer),
 	}
 }
 
-func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest, sessionID string) error {
+func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest, sessionID string) (_ *dto.MessageResponse, retErr error) {
 	// get information user login
 	token := ctx.Value("Authorization").(string)
 	userIDString, err := ms.jwt.GetUserIDByToken(token)
@@ -54,7 +98,7 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 			zap.String("access_token", token),
 			zap.Error(err),
 		)
-		return dto.ErrGetUserIDFromToken
+		return nil, dto.ErrGetUserIDFromToken
 	}
 	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
 	if err != nil {
@@ -62,13 +106,13 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 			zap.String("user_id", userIDString),
 			zap.Error(err),
 		)
-		return dto.ErrGetUserByID
+		return nil, dto.ErrGetUserByID
 	}
 	if !found {
 		ms.logger.Warn("user not found",
 			zap.String("user_id", userIDString),
 		)
-		return dto.ErrNotFound
+		return nil, dto.ErrNotFound
 	}
 
 	// validate active session
@@ -77,7 +121,10 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 		ms.logger.Warn("failed get active session by session id",
 			zap.String("session_id", sessionID),
 		)
-		return dto.ErrNotFound
+		return nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, err
 	}
 
 	// cannot send if session is not ongoing
@@ -86,21 +133,21 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 			zap.String("session_id", sessionID),
 			zap.Error(err),
 		)
-		return dto.ErrSessionWaiting
+		return nil, dto.ErrSessionWaiting
 	}
 	if session.Status == constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY {
 		ms.logger.Error("failed to send message because session is already process messages for summary",
 			zap.String("session_id", sessionID),
 			zap.Error(err),
 		)
-		return dto.ErrSessionFinished
+		return nil, dto.ErrSessionFinished
 	}
 	if session.Status == constants.ENUM_SESSION_STATUS_FINSIHED {
 		ms.logger.Error("failed to send message because session is finished",
 			zap.String("session_id", sessionID),
 			zap.Error(err),
 		)
-		return dto.ErrSessionFinished
+		return nil, dto.ErrSessionFinished
 	}
 
 	// parse session id
@@ -109,24 +156,105 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 		ms.logger.Error("failed parse session id to uuid",
 			zap.String("session_id", sessionID),
 		)
-		return dto.ErrParseStringToUUID
+		return nil, dto.ErrParseStringToUUID
 	}
 
-	// create message event
+	// id message diturunkan dari client message id supaya retry yang lolos dari redis tetap
+	// jatuh ke baris yang sama saat upsert ke postgres
 	msgID := uuid.New()
+	if req.ClientMessageID != "" {
+		msgID = uuid.NewSHA1(sID, []byte(user.ID.String()+":"+req.ClientMessageID))
+
+		existing, claimed, err := ms.claimClientMessageID(ctx, sessionID, msgID, user)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			ms.logger.Info("duplicate message send, returning original message",
+				zap.String("session_id", sessionID),
+				zap.String("message_id", msgID.String()),
+				zap.String("client_message_id", req.ClientMessageID),
+			)
+			return existing, nil
+		}
+		if claimed {
+			// kalau pengiriman gagal, client boleh retry dengan id yang sama
+			defer func() {
+				if retErr != nil {
+					ms.redis.Del(context.Background(), idempotencyKey(sessionID, msgID))
+				}
+			}()
+		}
+	}
+
+	// parent harus ada di session yang sama, reply ke reply digabung ke thread parent paling atas
+	if req.ParentMessageID != nil {
+		parent, _, _, err := ms.getMessage(ctx, sessionID, req.ParentMessageID.String())
+		if errors.Is(err, dto.ErrNotFound) {
+			ms.logger.Warn("parent message not found in session",
+				zap.String("session_id", sessionID),
+				zap.String("parent_message_id", req.ParentMessageID.String()),
+			)
+			return nil, dto.ErrParentMessageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.IsDeleted {
+			return nil, dto.ErrMessageAlreadyDeleted
+		}
+		if parent.ParentMessageID != nil {
+			req.ParentMessageID = parent.ParentMessageID
+		}
+	}
+
+	// file hanya boleh lewat attachment hasil upload, url bebas dari client ditolak
+	if req.FileURL != "" {
+		return nil, dto.ErrFileURLNotAllowed
+	}
+	attachmentIDs, attachments, err := ms.validateAttachments(ctx, req.AttachmentIDs, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// request REST sudah dibatasi per user di middleware, frame websocket dibatasi di sini.
+	// bucket per session melindungi sorted set & room session dari semua sender sekaligus
+	if ctx.Value("ConnectionID") != nil {
+		if _, err := ms.rateLimitService.Allow(ctx, RateLimitMessageSend, user.ID.String(), string(user.Role)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := ms.rateLimitService.Allow(ctx, RateLimitSessionMessage, sessionID, string(user.Role)); err != nil {
+		return nil, err
+	}
+
+	// seq diambil setelah semua validasi supaya request yang ditolak tidak membuat gap
+	seq, err := ms.messageRepo.NextMessageSeq(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed to get next message seq",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrNextMessageSeq
+	}
+
+	// create message event
+	now := time.Now()
 	messageEvent := &dto.MessageEventPublish{
 		Event:     "new_message",
 		MessageID: msgID,
+		Seq:       seq,
 		IsText:    req.IsText,
 		Text:      req.Text,
-		FileURL:   req.FileURL,
 		Sender: dto.CustomUserResponse{
 			ID:   user.ID,
 			Role: string(user.Role),
 		},
 		SessionID:       sID,
 		ParentMessageID: req.ParentMessageID,
-		Timestamp:       time.Now().Format(time.RFC3339Nano),
+		ClientMessageID: req.ClientMessageID,
+		Attachments:     attachments,
+		Timestamp:       now.Format(time.RFC3339Nano),
 	}
 
 	if user.LecturerID != nil {
@@ -139,77 +267,121 @@ func (ms *messageService) Send(ctx context.Context, req dto.SendMessageRequest,
 		messageEvent.Sender.Identifier = user.Student.Nim
 	}
 
+	messageEvent.Mentions = ms.resolveMentions(ctx, session, req.Text, user)
+
 	// save to Redis
-	key := fmt.Sprintf("session:%s:messages", sessionID)
 	data, err := json.Marshal(messageEvent)
 	if err != nil {
 		ms.logger.Error("failed marshal message to json", zap.Error(err))
-		return dto.ErrMarshalToJSON
+		return nil, dto.ErrMarshalToJSON
 	}
 	// save to Redis as sorted set
-	score := float64(time.Now().UnixNano()) // urut berdasarkan waktu
-	if err := ms.redis.ZAdd(ctx, key, redis.Z{
-		Score:  score,
-		Member: data,
-	}).Err(); err != nil {
-		ms.logger.Error("failed to ZADD message to redis",
-			zap.String("session_id", sessionID),
-			zap.Error(err),
-		)
-		return dto.ErrPushToRedis
+	zadd := func() error {
+		if err := ms.messageRepo.AddMessageToRedis(ctx, nil, sessionID, msgID, seq, data); err != nil {
+			ms.logger.Error("failed to ZADD message to redis",
+				zap.String("session_id", sessionID),
+				zap.Error(err),
+			)
+			return dto.ErrPushToRedis
+		}
+		return nil
 	}
+	if len(attachmentIDs) == 0 {
+		err = zadd()
+	} else {
+		// attachment di-claim dalam transaksi, kalau simpan ke redis gagal claim ikut di-rollback.
+		// sebaliknya kalau commit gagal setelah ZADD, message di redis dihapus lagi
+		added := false
+		err = ms.sessionRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+			linked, err := ms.attachmentRepo.LinkAttachments(ctx, tx, attachmentIDs, user.ID, msgID, sID)
+			if err != nil {
+				ms.logger.Error("failed to link attachments",
+					zap.String("message_id", msgID.String()),
+					zap.Error(err),
+				)
+				return dto.ErrLinkAttachments
+			}
+			if linked != int64(len(attachmentIDs)) {
+				return dto.ErrAttachmentAlreadyUsed
+			}
 
-	// set TTL expired
-	if err := ms.redis.Expire(ctx, key, 24*time.Hour).Err(); err != nil {
-		ms.logger.Warn("failed to set ttl for redis key",
-			zap.String("key", key),
-			zap.Error(err),
-		)
+			if err := zadd(); err != nil {
+				return err
+			}
+			added = true
+
+			return nil
+		})
+		if err != nil && added {
+			if remErr := ms.messageRepo.RemoveMessageFromRedis(context.Background(), nil, sessionID, msgID, data); remErr != nil {
+				ms.logger.Error("failed to remove message from redis after rollback",
+					zap.String("session_id", sessionID),
+					zap.String("message_id", msgID.String()),
+					zap.Error(remErr),
+				)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
+
 	ms.logger.Info("success to save message to redis",
 		zap.String("message_id", msgID.String()),
 		zap.String("session_id", sessionID),
 	)
 
-	var receiverUserIDs []uuid.UUID
-	// tambahkan mahasiswa
-	if session.Thesis.StudentID != uuid.Nil {
-		receiverUserIDs = append(receiverUserIDs, session.Thesis.StudentID)
-	}
-	// tambahkan semua dosen pembimbing
-	for _, sup := range session.Thesis.Supervisors {
-		receiverUserIDs = append(receiverUserIDs, sup.LecturerID)
-	}
-	// send message to all receiver
-	for _, receiverID := range receiverUserIDs {
-		receiverUser, found, err := ms.userRepo.GetUserByStudentOrLecturerID(ctx, nil, receiverID.String())
-		if err != nil {
-			ms.logger.Error("failed to resolve receiver user",
-				zap.String("receiver_entity_id", receiverID.String()),
-				zap.Error(err),
-			)
-			continue
-		}
-		if !found {
-			ms.logger.Warn("receiver user not found for entity_id",
-				zap.String("receiver_entity_id", receiverID.String()),
-			)
-			continue
-		}
+	dataEvent, _ := json.Marshal(messageEvent)
+	ms.broadcast(ctx, session, dataEvent)
 
-		dataEvent, _ := json.Marshal(messageEvent)
-		if err := ms.wsService.SendToUser(receiverUser.ID.String(), dataEvent); err != nil {
-			ms.logger.Error("failed to send websocket message",
-				zap.String("receiver_user_id", receiverUser.ID.String()),
-				zap.Error(err),
-			)
-		}
+	// pesan terkirim berarti sudah selesai mengetik
+	ms.stopTyping(ctx, session.ID, user)
+
+	ms.notifyMentions(ctx, session, messageEvent, messageEvent.Mentions)
+
+	res := &dto.MessageResponse{
+		ID:              messageEvent.MessageID,
+		Seq:             messageEvent.Seq,
+		IsText:          messageEvent.IsText,
+		Text:            messageEvent.Text,
+		FileURL:         messageEvent.FileURL,
+		Sender:          messageEvent.Sender,
+		ParentMessageID: messageEvent.ParentMessageID,
+		ClientMessageID: messageEvent.ClientMessageID,
+		Mentions:        messageEvent.Mentions,
+		Attachments:     messageEvent.Attachments,
+		Timestamp:       messageEvent.Timestamp,
+	}
+	if res.ParentMessageID != nil {
+		ms.publishThreadReply(ctx, session, res)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (ms *messageService) List(ctx context.Context, req response.PaginationRequest, sessionID string) (*dto.MessagePaginationResponse, error) {
+func (ms *messageService) List(ctx context.Context, req dto.MessageCursorRequest, sessionID string) (*dto.MessagePaginationResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return &dto.MessagePaginationResponse{}, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return &dto.MessagePaginationResponse{}, dto.ErrGetUserByID
+	}
+	if !found {
+		return &dto.MessagePaginationResponse{}, dto.ErrNotFound
+	}
+
 	// validate active session
 	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
 	if !found {
@@ -225,16 +397,25 @@ func (ms *messageService) List(ctx context.Context, req response.PaginationReque
 		)
 		return &dto.MessagePaginationResponse{}, dto.ErrGetActiveSessionBySessionID
 	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return &dto.MessagePaginationResponse{}, err
+	}
+
+	// keyset pagination: halaman tidak bergeser walaupun ada message baru masuk saat scroll
+	cursor, after, err := ms.parseCursor(&req, sessionID)
+	if err != nil {
+		return nil, err
+	}
 
 	var dataWithPaginate *dto.MessagePaginationRepositoryResponse
 	switch session.Status {
 	case constants.ENUM_SESSION_STATUS_ONGOING,
 		constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY:
 		// 2️⃣ Ambil dari Redis (chat live)
-		dataWithPaginate, err = ms.messageRepo.GetAllMessageFromRedisWithPagination(ctx, nil, req, session)
+		dataWithPaginate, err = ms.messageRepo.GetAllMessageFromRedisWithCursor(ctx, nil, session, cursor, after, req.Limit)
 	case constants.ENUM_SESSION_STATUS_FINSIHED:
 		// 3️⃣ Ambil dari DB (history)
-		dataWithPaginate, err = ms.messageRepo.GetAllMessageWithPagination(ctx, nil, req, session)
+		dataWithPaginate, err = ms.messageRepo.GetAllMessageWithCursor(ctx, nil, session, cursor, after, req.Limit)
 	default:
 		ms.logger.Warn("session status invalid for listing messages",
 			zap.String("session_id", sessionID),
@@ -249,17 +430,140 @@ func (ms *messageService) List(ctx context.Context, req response.PaginationReque
 		)
 		return nil, dto.ErrGetAllMessageWithPagination
 	}
-	ms.logger.Info("success get all messages with pagination",
-		zap.Int("page", dataWithPaginate.Page),
-		zap.Int("per_page", dataWithPaginate.PerPage),
-		zap.Int64("count", dataWithPaginate.Count),
+	ms.logger.Info("success get all messages with cursor",
+		zap.String("session_id", sessionID),
+		zap.Int("count", len(dataWithPaginate.Messages)),
+		zap.Bool("has_more", dataWithPaginate.HasMore),
+	)
+
+	return ms.toPaginationResponse(ctx, session, dataWithPaginate, req, after), nil
+}
+
+// Replies isi thread satu message dengan keyset pagination yang sama seperti List
+func (ms *messageService) Replies(ctx context.Context, req dto.MessageCursorRequest, sessionID, messageID string) (*dto.MessagePaginationResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
+	// validate active session
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		ms.logger.Warn("failed get active session by session id",
+			zap.String("session_id", sessionID),
+		)
+		return nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, err
+	}
+
+	parent, _, _, err := ms.getMessage(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, after, err := ms.parseCursor(&req, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataWithPaginate *dto.MessagePaginationRepositoryResponse
+	switch session.Status {
+	case constants.ENUM_SESSION_STATUS_ONGOING,
+		constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY:
+		dataWithPaginate, err = ms.messageRepo.GetRepliesFromRedisWithCursor(ctx, nil, session, parent.MessageID, cursor, after, req.Limit)
+	case constants.ENUM_SESSION_STATUS_FINSIHED:
+		dataWithPaginate, err = ms.messageRepo.GetRepliesWithCursor(ctx, nil, session, parent.MessageID, cursor, after, req.Limit)
+	default:
+		return nil, dto.ErrInvalidSessionStatus
+	}
+	if err != nil {
+		ms.logger.Error("failed to get message replies",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetMessageReplies
+	}
+	ms.logger.Info("success get message replies",
+		zap.String("session_id", sessionID),
+		zap.String("message_id", messageID),
+		zap.Int("count", len(dataWithPaginate.Messages)),
 	)
 
+	return ms.toPaginationResponse(ctx, session, dataWithPaginate, req, after), nil
+}
+
+// parseCursor validasi before / after dan normalisasi limit, after true berarti mode after
+func (ms *messageService) parseCursor(req *dto.MessageCursorRequest, sessionID string) (*dto.MessageCursor, bool, error) {
+	if req.Before != "" && req.After != "" {
+		return nil, false, dto.ErrInvalidCursor
+	}
+	if req.Limit <= 0 {
+		req.Limit = messageDefaultLimit
+	}
+	if req.Limit > messageMaxLimit {
+		req.Limit = messageMaxLimit
+	}
+
+	raw := req.Before + req.After
+	if raw == "" {
+		return nil, req.After != "", nil
+	}
+	seq, err := helper.DecodeCursor(raw)
+	if err != nil {
+		ms.logger.Warn("invalid message cursor",
+			zap.String("session_id", sessionID),
+			zap.String("cursor", raw),
+		)
+		return nil, false, dto.ErrInvalidCursor
+	}
+
+	return &dto.MessageCursor{Seq: seq}, req.After != "", nil
+}
+
+// toPaginationResponse mapping hasil repository ke response, lengkap dengan jumlah & reply terakhir tiap message
+func (ms *messageService) toPaginationResponse(ctx context.Context, session *entity.Session, dataWithPaginate *dto.MessagePaginationRepositoryResponse, req dto.MessageCursorRequest, after bool) *dto.MessagePaginationResponse {
+	ids := make([]uuid.UUID, 0, len(dataWithPaginate.Messages))
+	for _, message := range dataWithPaginate.Messages {
+		if message.ParentMessageID == nil {
+			ids = append(ids, message.ID)
+		}
+	}
+	stats := ms.threadStats(ctx, session, ids)
+	attachments := ms.messageAttachments(ctx, dataWithPaginate.Messages)
+
 	// loop for build responses
 	var datas []dto.MessageResponse
 	for _, message := range dataWithPaginate.Messages {
 		data := dto.MessageResponse{
 			ID:      message.ID,
+			Seq:     message.Seq,
 			IsText:  &message.IsText,
 			Text:    message.Text,
 			FileURL: message.FileURL,
@@ -268,8 +572,15 @@ func (ms *messageService) List(ctx context.Context, req response.PaginationReque
 				Role: string(message.Sender.Role),
 			},
 			ParentMessageID: message.ParentMessageID,
+			ClientMessageID: message.ClientMessageID,
+			Mentions:        message.Mentions,
+			IsEdited:        message.IsEdited,
+			IsDeleted:       message.IsDeleted,
 			Timestamp:       message.TimeStamp.CreatedAt.String(),
 		}
+		if message.EditedAt != nil {
+			data.EditedAt = message.EditedAt.Format(time.RFC3339Nano)
+		}
 
 		if message.Sender.LecturerID != nil {
 			data.Sender.Name = message.Sender.Lecturer.Name
@@ -281,16 +592,1179 @@ func (ms *messageService) List(ctx context.Context, req response.PaginationReque
 			data.Sender.Identifier = message.Sender.Student.Nim
 		}
 
+		if !message.IsDeleted {
+			data.Attachments = attachments[message.ID]
+		}
+
+		if stat, ok := stats[message.ID]; ok {
+			data.ReplyCount = stat.ReplyCount
+			data.LastReply = stat.LastReply
+		}
+
 		datas = append(datas, data)
 	}
 
+	// data selalu terbaru ke terlama. mode before lanjut dari yang paling lama, mode after dari yang
+	// paling baru (selalu diisi supaya client bisa polling message baru)
+	meta := dto.MessageCursorResponse{
+		Limit:   req.Limit,
+		HasMore: dataWithPaginate.HasMore,
+	}
+	messages := dataWithPaginate.Messages
+	if len(messages) > 0 {
+		if after {
+			meta.NextCursor = helper.EncodeCursor(messages[0].Seq)
+		} else if dataWithPaginate.HasMore {
+			last := messages[len(messages)-1]
+			meta.NextCursor = helper.EncodeCursor(last.Seq)
+		}
+	} else if after {
+		meta.NextCursor = req.After
+	}
+
 	return &dto.MessagePaginationResponse{
 		Data: datas,
-		PaginationResponse: response.PaginationResponse{
-			Page:    dataWithPaginate.Page,
-			PerPage: dataWithPaginate.PerPage,
-			MaxPage: dataWithPaginate.MaxPage,
-			Count:   dataWithPaginate.Count,
-		},
-	}, nil
+		Meta: meta,
+	}
+}
+
+// threadStats session live dihitung dari redis, session selesai dari postgres. gagal hitung tidak
+// menggagalkan list, reply_count cukup tampil 0
+func (ms *messageService) threadStats(ctx context.Context, session *entity.Session, parentIDs []uuid.UUID) map[uuid.UUID]dto.ThreadStats {
+	if len(parentIDs) == 0 {
+		return nil
+	}
+
+	var stats map[uuid.UUID]dto.ThreadStats
+	var err error
+	if session.Status == constants.ENUM_SESSION_STATUS_FINSIHED {
+		stats, err = ms.messageRepo.GetThreadStats(ctx, nil, session.ID.String(), parentIDs)
+	} else {
+		stats, err = ms.messageRepo.GetThreadStatsFromRedis(ctx, nil, session.ID.String())
+	}
+	if err != nil {
+		ms.logger.Warn("failed to get thread stats",
+			zap.String("session_id", session.ID.String()),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	return stats
+}
+
+// publishThreadReply event tambahan selain new_message supaya client bisa update counter thread
+// tanpa menghitung ulang sendiri
+func (ms *messageService) publishThreadReply(ctx context.Context, session *entity.Session, reply *dto.MessageResponse) {
+	parentID := *reply.ParentMessageID
+	event := dto.ThreadReplyEventPublish{
+		Event:           "thread_reply",
+		SessionID:       session.ID,
+		ParentMessageID: parentID,
+		Reply:           *reply,
+	}
+	if stat, ok := ms.threadStats(ctx, session, []uuid.UUID{parentID})[parentID]; ok {
+		event.ReplyCount = stat.ReplyCount
+		event.LastReply = stat.LastReply
+	}
+
+	data, _ := json.Marshal(event)
+	ms.broadcast(ctx, session, data)
+}
+
+func (ms *messageService) Edit(ctx context.Context, req dto.EditMessageRequest, sessionID, messageID string) (*dto.MessageResponse, error) {
+	if req.FileURL != "" {
+		return nil, dto.ErrFileURLNotAllowed
+	}
+
+	return ms.modify(ctx, sessionID, messageID, entity.MESSAGE_EDIT, func(evt *dto.MessageEventPublish, now time.Time) {
+		evt.Text = req.Text
+		evt.IsEdited = true
+		evt.EditedAt = now.Format(time.RFC3339Nano)
+	})
+}
+
+func (ms *messageService) Delete(ctx context.Context, sessionID, messageID string) (*dto.MessageResponse, error) {
+	// isi message dikosongkan, row tetap ada sebagai tombstone
+	return ms.modify(ctx, sessionID, messageID, entity.MESSAGE_DELETE, func(evt *dto.MessageEventPublish, now time.Time) {
+		evt.Text = ""
+		evt.FileURL = ""
+		evt.Attachments = nil
+		evt.IsDeleted = true
+	})
+}
+
+// Pin hanya dosen pembimbing thesis. pin disimpan di postgres, isi message diambil dari redis atau
+// postgres sesuai posisi message, jadi daftar pin tetap utuh setelah session selesai
+func (ms *messageService) Pin(ctx context.Context, sessionID, messageID string) (*dto.PinnedMessageResponse, error) {
+	user, session, evt, err := ms.pinTarget(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if evt.IsDeleted {
+		return nil, dto.ErrMessageAlreadyDeleted
+	}
+
+	pin := &entity.MessagePin{
+		ID:         uuid.New(),
+		SessionID:  session.ID,
+		MessageID:  evt.MessageID,
+		PinnedByID: user.ID,
+	}
+	created, err := ms.messageRepo.CreateMessagePin(ctx, nil, pin)
+	if err != nil {
+		ms.logger.Error("failed to create message pin",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrPinMessage
+	}
+	if !created {
+		return nil, dto.ErrMessageAlreadyPinned
+	}
+
+	res := &dto.PinnedMessageResponse{
+		Message:  *toEventResponse(evt),
+		PinnedBy: toCustomUserResponse(user),
+		PinnedAt: pin.CreatedAt.Format(time.RFC3339Nano),
+	}
+	ms.publishPin(ctx, session, "message_pinned", res)
+	ms.logger.Info("success pin message",
+		zap.String("session_id", sessionID),
+		zap.String("message_id", messageID),
+		zap.String("user_id", user.ID.String()),
+	)
+
+	return res, nil
+}
+
+func (ms *messageService) Unpin(ctx context.Context, sessionID, messageID string) (*dto.PinnedMessageResponse, error) {
+	user, session, evt, err := ms.pinTarget(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// pin dibaca dulu supaya response tetap berisi siapa & kapan message di-pin
+	pin, found, err := ms.messageRepo.GetMessagePin(ctx, nil, sessionID, evt.MessageID.String())
+	if err != nil {
+		ms.logger.Error("failed to get message pin",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrUnpinMessage
+	}
+	if !found {
+		return nil, dto.ErrMessageNotPinned
+	}
+
+	deleted, err := ms.messageRepo.DeleteMessagePin(ctx, nil, sessionID, evt.MessageID.String())
+	if err != nil {
+		ms.logger.Error("failed to delete message pin",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrUnpinMessage
+	}
+	if !deleted {
+		return nil, dto.ErrMessageNotPinned
+	}
+
+	unpinnedBy := toCustomUserResponse(user)
+	res := &dto.PinnedMessageResponse{
+		Message:    *toEventResponse(evt),
+		PinnedBy:   toCustomUserResponse(&pin.PinnedBy),
+		PinnedAt:   pin.CreatedAt.Format(time.RFC3339Nano),
+		UnpinnedBy: &unpinnedBy,
+		UnpinnedAt: time.Now().Format(time.RFC3339Nano),
+	}
+	ms.publishPin(ctx, session, "message_unpinned", res)
+	ms.logger.Info("success unpin message",
+		zap.String("session_id", sessionID),
+		zap.String("message_id", messageID),
+		zap.String("user_id", user.ID.String()),
+	)
+
+	return res, nil
+}
+
+// Pinned daftar pin session untuk semua participant, message yang sudah dihapus tidak ditampilkan
+func (ms *messageService) Pinned(ctx context.Context, sessionID string) ([]dto.PinnedMessageResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
+	// validate active session
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, err
+	}
+
+	pins, err := ms.messageRepo.GetMessagePinsBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed to get message pins",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetPinnedMessages
+	}
+
+	events := ms.pinnedMessages(ctx, session, pins)
+	res := make([]dto.PinnedMessageResponse, 0, len(pins))
+	for _, pin := range pins {
+		evt, ok := events[pin.MessageID]
+		if !ok || evt.IsDeleted {
+			continue
+		}
+
+		res = append(res, dto.PinnedMessageResponse{
+			Message:  *toEventResponse(evt),
+			PinnedBy: toCustomUserResponse(&pin.PinnedBy),
+			PinnedAt: pin.CreatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	ms.logger.Info("success get pinned messages",
+		zap.String("session_id", sessionID),
+		zap.Int("count", len(res)),
+	)
+
+	return res, nil
+}
+
+// pinnedMessages isi message yang di-pin: redis dibaca sekali untuk seluruh session, sisanya (sudah
+// tidak ada di redis) diambil dari postgres dalam satu query
+func (ms *messageService) pinnedMessages(ctx context.Context, session *entity.Session, pins []entity.MessagePin) map[uuid.UUID]*dto.MessageEventPublish {
+	res := make(map[uuid.UUID]*dto.MessageEventPublish, len(pins))
+	if len(pins) == 0 {
+		return res
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(pins))
+	for _, pin := range pins {
+		wanted[pin.MessageID] = true
+	}
+
+	live, err := ms.messageRepo.GetAllMessageFromRedis(ctx, nil, session)
+	if err != nil {
+		ms.logger.Warn("failed to get messages from redis for pins",
+			zap.String("session_id", session.ID.String()),
+			zap.Error(err),
+		)
+	}
+	if live != nil {
+		for i := range *live {
+			evt := &(*live)[i]
+			if wanted[evt.MessageID] {
+				res[evt.MessageID] = evt
+			}
+		}
+	}
+
+	missing := make([]uuid.UUID, 0, len(pins))
+	for id := range wanted {
+		if _, ok := res[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return res
+	}
+
+	messages, err := ms.messageRepo.GetMessagesByIDs(ctx, nil, session.ID.String(), missing)
+	if err != nil {
+		ms.logger.Warn("failed to get pinned messages from postgres",
+			zap.String("session_id", session.ID.String()),
+			zap.Error(err),
+		)
+		return res
+	}
+	attachments := ms.messageAttachments(ctx, messages)
+	for i := range messages {
+		evt := toMessageEvent(&messages[i], &messages[i].Sender)
+		if !evt.IsDeleted {
+			evt.Attachments = attachments[evt.MessageID]
+		}
+		res[evt.MessageID] = evt
+	}
+
+	return res
+}
+
+// pinTarget validasi yang sama untuk pin & unpin: participant session dan dosen pembimbing
+func (ms *messageService) pinTarget(ctx context.Context, sessionID, messageID string) (*entity.User, *entity.Session, *dto.MessageEventPublish, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, nil, nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, nil, nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, nil, nil, dto.ErrNotFound
+	}
+
+	// validate active session
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, nil, nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		return nil, nil, nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, nil, nil, err
+	}
+	// participant yang punya lecturer id pasti salah satu pembimbing thesis
+	if user.LecturerID == nil {
+		ms.logger.Warn("non lecturer tried to pin message",
+			zap.String("session_id", sessionID),
+			zap.String("user_id", userIDString),
+		)
+		return nil, nil, nil, dto.ErrNotLecturerPinMessage
+	}
+
+	evt, _, _, err := ms.getMessage(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return user, session, evt, nil
+}
+
+func (ms *messageService) publishPin(ctx context.Context, session *entity.Session, eventName string, pin *dto.PinnedMessageResponse) {
+	event := dto.MessagePinEventPublish{
+		Event:     eventName,
+		SessionID: session.ID,
+		Pin:       *pin,
+	}
+
+	data, _ := json.Marshal(event)
+	ms.broadcast(ctx, session, data)
+}
+
+func (ms *messageService) MarkRead(ctx context.Context, req dto.MarkReadRequest, sessionID string) (*dto.ReadCursorResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		ms.logger.Warn("user not found",
+			zap.String("user_id", userIDString),
+		)
+		return nil, dto.ErrNotFound
+	}
+
+	// validate active session
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		ms.logger.Warn("failed get active session by session id",
+			zap.String("session_id", sessionID),
+		)
+		return nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, err
+	}
+
+	evt, _, _, err := ms.getMessage(ctx, sessionID, req.MessageID.String())
+	if err != nil {
+		return nil, err
+	}
+	readAt, err := time.Parse(time.RFC3339Nano, evt.Timestamp)
+	if err != nil {
+		ms.logger.Warn("failed to parse message timestamp",
+			zap.String("message_id", evt.MessageID.String()),
+			zap.String("timestamp", evt.Timestamp),
+			zap.Error(err),
+		)
+		readAt = time.Now()
+	}
+
+	cursor := &entity.ReadCursor{
+		ID:                uuid.New(),
+		UserID:            user.ID,
+		SessionID:         session.ID,
+		LastReadMessageID: evt.MessageID,
+		LastReadSeq:       evt.Seq,
+		LastReadAt:        readAt,
+	}
+	if err := ms.messageRepo.UpsertReadCursor(ctx, nil, cursor); err != nil {
+		ms.logger.Error("failed to upsert read cursor",
+			zap.String("session_id", sessionID),
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrUpsertReadCursor
+	}
+
+	// ambil ulang, cursor yang tersimpan bisa lebih baru dari request
+	current, found, err := ms.messageRepo.GetReadCursor(ctx, nil, sessionID, userIDString)
+	if err != nil || !found {
+		ms.logger.Error("failed to get read cursor",
+			zap.String("session_id", sessionID),
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrUpsertReadCursor
+	}
+	ms.logger.Info("success mark read",
+		zap.String("session_id", sessionID),
+		zap.String("user_id", userIDString),
+		zap.String("last_read_message_id", current.LastReadMessageID.String()),
+	)
+
+	res := &dto.ReadCursorResponse{
+		SessionID:         current.SessionID,
+		UserID:            current.UserID,
+		LastReadMessageID: current.LastReadMessageID,
+		LastReadSeq:       current.LastReadSeq,
+		LastReadAt:        current.LastReadAt.Format(time.RFC3339Nano),
+	}
+
+	data, _ := json.Marshal(dto.MessageReadEventPublish{
+		Event:             "message_read",
+		SessionID:         res.SessionID,
+		UserID:            res.UserID,
+		LastReadMessageID: res.LastReadMessageID,
+		LastReadSeq:       res.LastReadSeq,
+		LastReadAt:        res.LastReadAt,
+	})
+	ms.broadcast(ctx, session, data)
+
+	return res, nil
+}
+
+// Typing mengirim typing_started / typing_stopped ke participant lain di room session
+func (ms *messageService) Typing(ctx context.Context, sessionID string, isTyping bool) error {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return dto.ErrGetUserByID
+	}
+	if !found {
+		return dto.ErrNotFound
+	}
+
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		return dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		return dto.ErrNotFound
+	}
+	if session.Status != constants.ENUM_SESSION_STATUS_ONGOING {
+		return dto.ErrSessionNotOngoing
+	}
+
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return err
+	}
+
+	if isTyping {
+		ms.startTyping(ctx, session.ID, user)
+		return nil
+	}
+	ms.stopTyping(ctx, session.ID, user)
+
+	return nil
+}
+
+func typingKey(sessionID uuid.UUID, userID uuid.UUID) string {
+	return fmt.Sprintf("session:%s:typing:%s", sessionID, userID)
+}
+
+// startTyping simpan state typing di redis dengan TTL dan pasang timer, kalau client tidak
+// refresh / kirim typing_stopped maka server yang mengirim typing_stopped
+func (ms *messageService) startTyping(ctx context.Context, sessionID uuid.UUID, user *entity.User) {
+	key := typingKey(sessionID, user.ID)
+	expiresAt := time.Now().Add(typingTTL)
+	if err := ms.redis.Set(ctx, key, expiresAt.UnixMilli(), typingTTL).Err(); err != nil {
+		ms.logger.Warn("failed to set typing state",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+	ms.publishTyping(sessionID, user, "typing_started", &expiresAt)
+
+	ms.typingMu.Lock()
+	defer ms.typingMu.Unlock()
+
+	if timer, ok := ms.typingTimers[key]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(typingTTL+time.Second, func() {
+		ms.typingMu.Lock()
+		if ms.typingTimers[key] == timer {
+			delete(ms.typingTimers, key)
+		}
+		ms.typingMu.Unlock()
+
+		// device / instance lain bisa saja sudah memperpanjang typing
+		if n, err := ms.redis.Exists(context.Background(), key).Result(); err == nil && n > 0 {
+			return
+		}
+		ms.publishTyping(sessionID, user, "typing_stopped", nil)
+	})
+	ms.typingTimers[key] = timer
+}
+
+// stopTyping hanya broadcast kalau user memang sedang typing
+func (ms *messageService) stopTyping(ctx context.Context, sessionID uuid.UUID, user *entity.User) {
+	key := typingKey(sessionID, user.ID)
+
+	ms.typingMu.Lock()
+	if timer, ok := ms.typingTimers[key]; ok {
+		timer.Stop()
+		delete(ms.typingTimers, key)
+	}
+	ms.typingMu.Unlock()
+
+	deleted, err := ms.redis.Del(ctx, key).Result()
+	if err != nil {
+		ms.logger.Warn("failed to delete typing state",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return
+	}
+	if deleted == 0 {
+		return
+	}
+	ms.publishTyping(sessionID, user, "typing_stopped", nil)
+}
+
+// publishTyping typing bersifat ephemeral: tidak masuk log replay, redis message maupun postgres
+func (ms *messageService) publishTyping(sessionID uuid.UUID, user *entity.User, eventName string, expiresAt *time.Time) {
+	event := dto.TypingEventPublish{
+		Event:     eventName,
+		SessionID: sessionID,
+		UserID:    user.ID,
+		IsTyping:  eventName == "typing_started",
+		ExpiresAt: expiresAt,
+	}
+	if user.LecturerID != nil {
+		event.Name = user.Lecturer.Name
+	}
+	if user.StudentID != nil {
+		event.Name = user.Student.Name
+	}
+
+	data, _ := json.Marshal(event)
+	if err := ms.wsService.BroadcastToRoom(SessionRoom(sessionID.String()), nil, data); err != nil {
+		ms.logger.Error("failed to broadcast typing event",
+			zap.String("session_id", sessionID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// modify dipakai edit & hapus: validasi pengirim, simpan history, update redis + postgres, lalu broadcast
+func (ms *messageService) modify(ctx context.Context, sessionID, messageID string, action entity.MessageAction, apply func(evt *dto.MessageEventPublish, now time.Time)) (*dto.MessageResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ms.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ms.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ms.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ms.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		ms.logger.Warn("user not found",
+			zap.String("user_id", userIDString),
+		)
+		return nil, dto.ErrNotFound
+	}
+
+	// validate active session
+	session, found, err := ms.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ms.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		ms.logger.Warn("failed get active session by session id",
+			zap.String("session_id", sessionID),
+		)
+		return nil, dto.ErrNotFound
+	}
+	if err := ms.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return nil, err
+	}
+	if session.Status != constants.ENUM_SESSION_STATUS_ONGOING {
+		ms.logger.Warn("failed to modify message because session is not ongoing",
+			zap.String("session_id", sessionID),
+			zap.String("status", string(session.Status)),
+		)
+		return nil, dto.ErrSessionNotOngoing
+	}
+
+	evt, member, inRedis, err := ms.getMessage(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if evt.Sender.ID != user.ID {
+		ms.logger.Warn("failed to modify message because not the sender",
+			zap.String("message_id", messageID),
+			zap.String("sender_id", evt.Sender.ID.String()),
+			zap.String("user_id", userIDString),
+		)
+		return nil, dto.ErrNotMessageSender
+	}
+	if evt.IsDeleted {
+		return nil, dto.ErrMessageAlreadyDeleted
+	}
+
+	now := time.Now()
+	history := &entity.MessageEdit{
+		ID:              uuid.New(),
+		Action:          action,
+		PreviousText:    evt.Text,
+		PreviousFileURL: evt.FileURL,
+		MessageID:       evt.MessageID,
+		SessionID:       session.ID,
+		EditorID:        user.ID,
+	}
+	apply(evt, now)
+
+	// mention ikut isi terbaru, message yang dihapus tidak lagi me-mention siapa pun
+	previousMentions := evt.Mentions
+	evt.Mentions = nil
+	if action == entity.MESSAGE_EDIT {
+		evt.Mentions = ms.resolveMentions(ctx, session, evt.Text, user)
+	}
+
+	message := &entity.Message{
+		ID:        evt.MessageID,
+		Text:      evt.Text,
+		FileURL:   evt.FileURL,
+		Mentions:  evt.Mentions,
+		IsEdited:  evt.IsEdited,
+		IsDeleted: evt.IsDeleted,
+	}
+	if evt.IsEdited {
+		editedAt, err := time.Parse(time.RFC3339Nano, evt.EditedAt)
+		if err == nil {
+			message.EditedAt = &editedAt
+		}
+	}
+
+	// postgres hanya di-update kalau row sudah pernah di-checkpoint, redis diganti paling akhir
+	// supaya kalau gagal transaksi ikut di-rollback
+	err = ms.sessionRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if err := ms.messageRepo.CreateMessageEdit(ctx, tx, history); err != nil {
+			return dto.ErrCreateMessageEdit
+		}
+		if err := ms.messageRepo.UpdateMessage(ctx, tx, message); err != nil {
+			return dto.ErrUpdateMessage
+		}
+		if inRedis {
+			replaced, err := ms.messageRepo.ReplaceMessageInRedis(ctx, nil, sessionID, member, evt)
+			if err != nil {
+				return dto.ErrPushToRedis
+			}
+			if !replaced {
+				return dto.ErrMessageModifiedConcurrently
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		ms.logger.Error("failed to modify message",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.String("action", string(action)),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	ms.logger.Info("success modify message",
+		zap.String("session_id", sessionID),
+		zap.String("message_id", messageID),
+		zap.String("action", string(action)),
+	)
+
+	// nama event hanya untuk broadcast, record di redis tetap menyimpan event aslinya
+	published := *evt
+	published.Event = "message_edited"
+	if action == entity.MESSAGE_DELETE {
+		published.Event = "message_deleted"
+	}
+	data, err := json.Marshal(published)
+	if err != nil {
+		ms.logger.Error("failed marshal message to json", zap.Error(err))
+		return nil, dto.ErrMarshalToJSON
+	}
+	ms.broadcast(ctx, session, data)
+
+	// hanya user yang baru di-mention lewat edit yang dinotifikasi
+	var added []entity.MessageMention
+	for _, mention := range evt.Mentions {
+		if !hasMention(previousMentions, mention.UserID) {
+			added = append(added, mention)
+		}
+	}
+	ms.notifyMentions(ctx, session, evt, added)
+
+	return toEventResponse(evt), nil
+}
+
+// getMessage mencari message di redis (chat live), fallback ke postgres kalau key redis sudah tidak ada
+func (ms *messageService) getMessage(ctx context.Context, sessionID, messageID string) (*dto.MessageEventPublish, *redis.Z, bool, error) {
+	evt, member, inRedis, err := ms.messageRepo.GetMessageFromRedisByID(ctx, nil, sessionID, messageID)
+	if err != nil {
+		ms.logger.Error("failed get message from redis",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, nil, false, dto.ErrGetMessageByID
+	}
+	if inRedis {
+		return evt, member, true, nil
+	}
+
+	message, found, err := ms.messageRepo.GetMessageByID(ctx, nil, sessionID, messageID)
+	if err != nil {
+		ms.logger.Error("failed get message by id",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return nil, nil, false, dto.ErrGetMessageByID
+	}
+	if !found {
+		ms.logger.Warn("message not found",
+			zap.String("session_id", sessionID),
+			zap.String("message_id", messageID),
+		)
+		return nil, nil, false, dto.ErrNotFound
+	}
+
+	evt = toMessageEvent(message, &message.Sender)
+	if !evt.IsDeleted {
+		evt.Attachments = ms.messageAttachments(ctx, []entity.Message{*message})[message.ID]
+	}
+
+	return evt, nil, false, nil
+}
+
+func idempotencyKey(sessionID string, messageID uuid.UUID) string {
+	return fmt.Sprintf("session:%s:idempotency:%s", sessionID, messageID)
+}
+
+// claimClientMessageID menandai id message sedang dikirim (SETNX). kalau sudah pernah dikirim,
+// message aslinya dikembalikan, kalau masih dikirim oleh request lain return ErrMessageSendInProgress
+func (ms *messageService) claimClientMessageID(ctx context.Context, sessionID string, msgID uuid.UUID, user *entity.User) (*dto.MessageResponse, bool, error) {
+	key := idempotencyKey(sessionID, msgID)
+	claimed, err := ms.redis.SetNX(ctx, key, time.Now().UnixMilli(), idempotencyWindow).Result()
+	if err != nil {
+		ms.logger.Error("failed to claim client message id",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return nil, false, dto.ErrPushToRedis
+	}
+
+	if claimed {
+		// window redis sudah lewat tapi message bisa saja sudah dipersist
+		message, found, err := ms.messageRepo.GetMessageByID(ctx, nil, sessionID, msgID.String())
+		if err != nil {
+			ms.logger.Error("failed get message by id",
+				zap.String("session_id", sessionID),
+				zap.String("message_id", msgID.String()),
+				zap.Error(err),
+			)
+			ms.redis.Del(ctx, key)
+			return nil, false, dto.ErrGetMessageByID
+		}
+		if !found {
+			return nil, true, nil
+		}
+
+		evt := toMessageEvent(message, user)
+		evt.Attachments = ms.messageAttachments(ctx, []entity.Message{*message})[message.ID]
+		return toEventResponse(evt), false, nil
+	}
+
+	evt, _, _, err := ms.getMessage(ctx, sessionID, msgID.String())
+	if errors.Is(err, dto.ErrNotFound) {
+		// request pertama masih berjalan
+		return nil, false, dto.ErrMessageSendInProgress
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return toEventResponse(evt), false, nil
+}
+
+func toEventResponse(evt *dto.MessageEventPublish) *dto.MessageResponse {
+	return &dto.MessageResponse{
+		ID:              evt.MessageID,
+		Seq:             evt.Seq,
+		IsText:          evt.IsText,
+		Text:            evt.Text,
+		FileURL:         evt.FileURL,
+		Sender:          evt.Sender,
+		ParentMessageID: evt.ParentMessageID,
+		ClientMessageID: evt.ClientMessageID,
+		Mentions:        evt.Mentions,
+		Attachments:     evt.Attachments,
+		IsEdited:        evt.IsEdited,
+		EditedAt:        evt.EditedAt,
+		IsDeleted:       evt.IsDeleted,
+		Timestamp:       evt.Timestamp,
+	}
+}
+
+// validateAttachments attachment harus milik pengirim dan belum dipakai message lain
+func (ms *messageService) validateAttachments(ctx context.Context, ids []uuid.UUID, user *entity.User) ([]uuid.UUID, []dto.AttachmentResponse, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > messageMaxAttachments {
+		return nil, nil, dto.ErrTooManyAttachments
+	}
+
+	attachments, err := ms.attachmentRepo.GetAttachmentsByIDs(ctx, nil, unique)
+	if err != nil {
+		ms.logger.Error("failed to get attachments by ids",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err),
+		)
+		return nil, nil, dto.ErrGetAttachments
+	}
+	if len(attachments) != len(unique) {
+		return nil, nil, dto.ErrAttachmentNotFound
+	}
+
+	// urutan response mengikuti urutan attachment_ids dari client
+	byID := make(map[uuid.UUID]entity.Attachment, len(attachments))
+	for _, attachment := range attachments {
+		if attachment.UploaderID != user.ID {
+			ms.logger.Warn("attachment belongs to another user",
+				zap.String("attachment_id", attachment.ID.String()),
+				zap.String("user_id", user.ID.String()),
+			)
+			return nil, nil, dto.ErrNotAttachmentOwner
+		}
+		if attachment.MessageID != nil {
+			return nil, nil, dto.ErrAttachmentAlreadyUsed
+		}
+		byID[attachment.ID] = attachment
+	}
+
+	res := make([]dto.AttachmentResponse, 0, len(unique))
+	for _, id := range unique {
+		res = append(res, toAttachmentResponse(byID[id]))
+	}
+
+	return unique, res, nil
+}
+
+// messageAttachments attachment selalu tercatat di postgres sejak message dikirim, jadi dipakai untuk
+// message live maupun yang sudah dipersist. gagal ambil tidak menggagalkan response
+func (ms *messageService) messageAttachments(ctx context.Context, messages []entity.Message) map[uuid.UUID][]dto.AttachmentResponse {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	attachments, err := ms.attachmentRepo.GetAttachmentsByMessageIDs(ctx, nil, ids)
+	if err != nil {
+		ms.logger.Warn("failed to get message attachments",
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	res := make(map[uuid.UUID][]dto.AttachmentResponse)
+	for _, attachment := range attachments {
+		res[*attachment.MessageID] = append(res[*attachment.MessageID], toAttachmentResponse(attachment))
+	}
+
+	return res
+}
+
+func toCustomUserResponse(user *entity.User) dto.CustomUserResponse {
+	res := dto.CustomUserResponse{
+		ID:   user.ID,
+		Role: string(user.Role),
+	}
+	if user.LecturerID != nil {
+		res.Name = user.Lecturer.Name
+		res.Identifier = user.Lecturer.Nip
+	}
+	if user.StudentID != nil {
+		res.Name = user.Student.Name
+		res.Identifier = user.Student.Nim
+	}
+
+	return res
+}
+
+func toMessageEvent(message *entity.Message, sender *entity.User) *dto.MessageEventPublish {
+	isText := message.IsText
+	evt := &dto.MessageEventPublish{
+		MessageID: message.ID,
+		Seq:       message.Seq,
+		IsText:    &isText,
+		Text:      message.Text,
+		FileURL:   message.FileURL,
+		Sender: dto.CustomUserResponse{
+			ID:   message.SenderID,
+			Role: string(message.SenderRole),
+		},
+		SessionID:       message.SessionID,
+		ParentMessageID: message.ParentMessageID,
+		ClientMessageID: message.ClientMessageID,
+		Mentions:        message.Mentions,
+		IsEdited:        message.IsEdited,
+		IsDeleted:       message.IsDeleted,
+		Timestamp:       message.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if message.EditedAt != nil {
+		evt.EditedAt = message.EditedAt.Format(time.RFC3339Nano)
+	}
+
+	if sender.LecturerID != nil {
+		evt.Sender.Name = sender.Lecturer.Name
+		evt.Sender.Identifier = sender.Lecturer.Nip
+	}
+	if sender.StudentID != nil {
+		evt.Sender.Name = sender.Student.Name
+		evt.Sender.Identifier = sender.Student.Nim
+	}
+
+	return evt
+}
+
+// resolveMentions mencocokkan @nip / @nim dengan pembimbing dan mahasiswa thesis session,
+// identifier di luar session dan mention ke diri sendiri diabaikan
+func (ms *messageService) resolveMentions(ctx context.Context, session *entity.Session, text string, sender *entity.User) []entity.MessageMention {
+	identifiers := helper.ParseMentions(text)
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		targetID   uuid.UUID
+		identifier string
+		name       string
+		role       entity.Role
+	}
+	candidates := make(map[string]candidate)
+	student := session.Thesis.Student
+	candidates[strings.ToLower(student.Nim)] = candidate{student.ID, student.Nim, student.Name, entity.STUDENT}
+	for _, sup := range session.Thesis.Supervisors {
+		candidates[strings.ToLower(sup.Lecturer.Nip)] = candidate{sup.LecturerID, sup.Lecturer.Nip, sup.Lecturer.Name, sup.Role}
+	}
+
+	var mentions []entity.MessageMention
+	for _, identifier := range identifiers {
+		target, ok := candidates[strings.ToLower(identifier)]
+		if !ok {
+			continue
+		}
+
+		user, found, err := ms.userRepo.GetUserByStudentOrLecturerID(ctx, nil, target.targetID.String())
+		if err != nil || !found {
+			ms.logger.Warn("failed to resolve mentioned user",
+				zap.String("session_id", session.ID.String()),
+				zap.String("identifier", target.identifier),
+				zap.Error(err),
+			)
+			continue
+		}
+		if user.ID == sender.ID {
+			continue
+		}
+
+		mentions = append(mentions, entity.MessageMention{
+			UserID:     user.ID,
+			Identifier: target.identifier,
+			Name:       target.name,
+			Role:       target.role,
+		})
+	}
+
+	return mentions
+}
+
+// notifyMentions notifikasi mention selalu disimpan dengan prioritas tinggi walaupun user sedang online,
+// lalu event mention dikirim langsung ke user tersebut
+func (ms *messageService) notifyMentions(ctx context.Context, session *entity.Session, evt *dto.MessageEventPublish, mentions []entity.MessageMention) {
+	for _, mention := range mentions {
+		notif := &entity.Notification{
+			ID:       uuid.New(),
+			Title:    "New Mention",
+			Message:  fmt.Sprintf("%s mentioned you in thesis session \"%s\": %s", evt.Sender.Name, session.Thesis.Title, evt.Text),
+			IsRead:   false,
+			Priority: entity.NOTIFICATION_HIGH,
+			UserID:   mention.UserID,
+		}
+		if err := ms.notificationRepo.CreateNotification(ctx, nil, notif); err != nil {
+			ms.logger.Error("failed to create mention notification",
+				zap.String("session_id", session.ID.String()),
+				zap.String("message_id", evt.MessageID.String()),
+				zap.String("receiver_user_id", mention.UserID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		data, _ := json.Marshal(dto.MentionEventPublish{
+			Event:          "mention",
+			Priority:       string(entity.NOTIFICATION_HIGH),
+			NotificationID: notif.ID,
+			SessionID:      session.ID,
+			ThesisID:       session.ThesisID,
+			MessageID:      evt.MessageID,
+			Sender:         evt.Sender,
+			Text:           evt.Text,
+			Timestamp:      evt.Timestamp,
+		})
+		if err := ms.wsService.SendToUser(mention.UserID.String(), data); err != nil {
+			ms.logger.Info("mentioned user is offline",
+				zap.String("receiver_user_id", mention.UserID.String()),
+			)
+		}
+	}
+}
+
+func hasMention(mentions []entity.MessageMention, userID uuid.UUID) bool {
+	for _, mention := range mentions {
+		if mention.UserID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// broadcast mengirim event ke room session dalam satu publish, hanya koneksi peserta
+// yang sudah subscribe yang menerima. event juga dicatat di log semua peserta untuk replay
+func (ms *messageService) broadcast(ctx context.Context, session *entity.Session, data []byte) {
+	userIDs, err := ms.participantService.GetSessionParticipantUserIDs(ctx, session.ID.String())
+	if err != nil {
+		ms.logger.Warn("failed to get session participants for event log",
+			zap.String("session_id", session.ID.String()),
+			zap.Error(err),
+		)
+	}
+
+	if err := ms.wsService.BroadcastToRoom(SessionRoom(session.ID.String()), userIDs, data); err != nil {
+		ms.logger.Error("failed to broadcast websocket message",
+			zap.String("session_id", session.ID.String()),
+			zap.Error(err),
+		)
+	}
 }
@@ -55,6 +55,7 @@ func (ns *notificationService) GetAll(ctx context.Context) ([]*dto.NotificationR
 			Title:     data.Title,
 			Message:   data.Message,
 			IsRead:    data.IsRead,
+			Priority:  string(data.Priority),
 			CreatedAt: data.CreatedAt,
 		})
 	}
@@ -101,6 +102,7 @@ func (ns *notificationService) GetDetail(ctx context.Context, id *string) (*dto.
 		Title:     data.Title,
 		Message:   data.Message,
 		IsRead:    data.IsRead,
+		Priority:  string(data.Priority),
 		CreatedAt: data.CreatedAt,
 	}
 	ns.logger.Info("success get detail notification",
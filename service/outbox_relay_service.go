@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	outboxRelayInterval  = 2 * time.Second
+	outboxRelayBatchSize = 20
+	outboxConfirmTimeout = 5 * time.Second
+	outboxLeaseDuration  = outboxRelayBatchSize*outboxConfirmTimeout + time.Minute
+	outboxBaseBackoff    = 2 * time.Second
+	outboxMaxBackoff     = 5 * time.Minute
+	outboxMaxErrorLength = 500
+)
+
+var (
+	errChannelNotReady     = errors.New("rabbitmq connection not ready")
+	errPublishNotConfirmed = errors.New("publish not confirmed by broker")
+)
+
+type (
+	IOutboxRelayService interface {
+		Run(ctx context.Context)
+		RelayPending(ctx context.Context) (int, error)
+	}
+
+	outboxRelayService struct {
+		outboxRepo repository.IOutboxRepository
+		logger     *zap.Logger
+		rabbitmq   *amqp091.Connection
+		channel    *amqp091.Channel
+		declared   map[string]bool
+	}
+)
+
+func NewOutboxRelayService(outboxRepo repository.IOutboxRepository, logger *zap.Logger, rabbitmq *amqp091.Connection) *outboxRelayService {
+	return &outboxRelayService{
+		outboxRepo: outboxRepo,
+		logger:     logger,
+		rabbitmq:   rabbitmq,
+		declared:   make(map[string]bool),
+	}
+}
+
+// Run mem-publish row outbox yang masih pending secara berkala
+func (ors *outboxRelayService) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+	defer ors.closeChannel()
+
+	ors.logger.Info("outbox relay started",
+		zap.Duration("interval", outboxRelayInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			ors.logger.Info("outbox relay stopped")
+			return
+		case <-ticker.C:
+			if _, err := ors.RelayPending(ctx); err != nil {
+				ors.logger.Error("failed to relay outbox",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// RelayPending row di-claim (lease) dalam transaksi singkat, publish ke rabbitmq dilakukan di luar
+// transaksi supaya lock row & koneksi db tidak tertahan selama menunggu confirm broker
+func (ors *outboxRelayService) RelayPending(ctx context.Context) (int, error) {
+	var outboxes []*entity.Outbox
+	err := ors.outboxRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		pending, err := ors.outboxRepo.GetPendingOutboxes(ctx, tx, outboxRelayBatchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, outbox := range pending {
+			ids = append(ids, outbox.ID.String())
+		}
+		if err := ors.outboxRepo.LeaseOutboxes(ctx, tx, ids, time.Now().Add(outboxLeaseDuration)); err != nil {
+			return err
+		}
+		outboxes = pending
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, outbox := range outboxes {
+		if err := ors.publish(ctx, outbox); err != nil {
+			attempts := outbox.Attempts + 1
+			nextAttemptAt := time.Now().Add(outboxBackoff(attempts))
+			ors.logger.Warn("failed to publish outbox, retry later",
+				zap.String("outbox_id", outbox.ID.String()),
+				zap.String("queue", outbox.Queue),
+				zap.Int("attempts", attempts),
+				zap.Time("next_attempt_at", nextAttemptAt),
+				zap.Error(err),
+			)
+
+			if err := ors.outboxRepo.MarkOutboxFailed(ctx, nil, outbox.ID.String(), attempts, nextAttemptAt, truncateError(err)); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		// kalau gagal ditandai, row di-publish ulang setelah lease habis. relay ini at-least-once, worker summary
+		// bisa menerima task yang sama lebih dari sekali (MessageId = outbox id, tapi worker tidak dedupe)
+		if err := ors.outboxRepo.MarkOutboxSent(ctx, nil, outbox.ID.String()); err != nil {
+			return sent, err
+		}
+		sent++
+
+		ors.logger.Info("success publish outbox",
+			zap.String("outbox_id", outbox.ID.String()),
+			zap.String("queue", outbox.Queue),
+			zap.String("aggregate_id", outbox.AggregateID.String()),
+		)
+	}
+
+	return sent, nil
+}
+
+func (ors *outboxRelayService) publish(ctx context.Context, outbox *entity.Outbox) error {
+	ch, err := ors.getChannel()
+	if err != nil {
+		return err
+	}
+
+	if !ors.declared[outbox.Queue] {
+		if _, err := ch.QueueDeclare(
+			outbox.Queue,
+			true,  // durable
+			false, // autoDelete
+			false, // exclusive
+			false, // noWait
+			nil,   // args
+		); err != nil {
+			ors.closeChannel()
+			return fmt.Errorf("failed to declare queue: %w", err)
+		}
+		ors.declared[outbox.Queue] = true
+	}
+
+	ct, cancel := context.WithTimeout(ctx, outboxConfirmTimeout)
+	defer cancel()
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(
+		ct,
+		"",           // exchange
+		outbox.Queue, // routing key
+		false,        // mandatory
+		false,        // immediate
+		amqp091.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp091.Persistent,
+			MessageId:    outbox.ID.String(),
+			Body:         []byte(outbox.Payload),
+		},
+	)
+	if err != nil {
+		ors.closeChannel()
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ct)
+	if err != nil {
+		ors.closeChannel()
+		return fmt.Errorf("failed to wait publisher confirm: %w", err)
+	}
+	if !acked {
+		return errPublishNotConfirmed
+	}
+
+	return nil
+}
+
+func (ors *outboxRelayService) getChannel() (*amqp091.Channel, error) {
+	if ors.channel != nil && !ors.channel.IsClosed() {
+		return ors.channel, nil
+	}
+
+	if ors.rabbitmq == nil || ors.rabbitmq.IsClosed() {
+		return nil, errChannelNotReady
+	}
+
+	ch, err := ors.rabbitmq.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	// publisher confirm: broker wajib ack setiap publish
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	ors.channel = ch
+	ors.declared = make(map[string]bool)
+	return ch, nil
+}
+
+func (ors *outboxRelayService) closeChannel() {
+	if ors.channel != nil {
+		ors.channel.Close()
+		ors.channel = nil
+	}
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Duration(float64(outboxBaseBackoff) * math.Pow(2, float64(attempts-1)))
+	if backoff <= 0 || backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+
+	return backoff
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > outboxMaxErrorLength {
+		return msg[:outboxMaxErrorLength]
+	}
+
+	return msg
+}
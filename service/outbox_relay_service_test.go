@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 2 * time.Second},
+		{attempts: 2, want: 4 * time.Second},
+		{attempts: 3, want: 8 * time.Second},
+		{attempts: 8, want: 256 * time.Second},
+		{attempts: 9, want: outboxMaxBackoff},
+		{attempts: 64, want: outboxMaxBackoff},
+		{attempts: 10000, want: outboxMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateError(t *testing.T) {
+	short := errors.New("connection refused")
+	if got := truncateError(short); got != short.Error() {
+		t.Errorf("truncateError(short) = %q, want %q", got, short.Error())
+	}
+
+	long := errors.New(strings.Repeat("x", outboxMaxErrorLength+10))
+	if got := truncateError(long); len(got) != outboxMaxErrorLength {
+		t.Errorf("truncateError(long) length = %d, want %d", len(got), outboxMaxErrorLength)
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const sessionParticipantsTTL = 24 * time.Hour
+
+type (
+	IParticipantService interface {
+		GetSessionParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error)
+		IsSessionParticipant(ctx context.Context, sessionID, userID string) (bool, error)
+		AuthorizeSession(ctx context.Context, session *entity.Session, user *entity.User) error
+		AuthorizeThesis(ctx context.Context, thesis *entity.Thesis, user *entity.User) error
+		SubscribeSession(ctx context.Context, sessionID string) error
+		UnsubscribeSession(ctx context.Context, sessionID string) error
+	}
+
+	participantService struct {
+		participantRepo repository.IParticipantRepository
+		sessionRepo     repository.ISessionRepository
+		userRepo        repository.IUserRepository
+		logger          *zap.Logger
+		wsService       IWebsocketService
+		jwt             jwt.IJWT
+	}
+)
+
+func NewParticipantService(participantRepo repository.IParticipantRepository, sessionRepo repository.ISessionRepository, userRepo repository.IUserRepository, logger *zap.Logger, wsService IWebsocketService, jwt jwt.IJWT) *participantService {
+	return &participantService{
+		participantRepo: participantRepo,
+		sessionRepo:     sessionRepo,
+		userRepo:        userRepo,
+		logger:          logger,
+		wsService:       wsService,
+		jwt:             jwt,
+	}
+}
+
+// SessionRoom nama room websocket untuk sebuah session
+func SessionRoom(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// GetSessionParticipantUserIDs user id mahasiswa & dosen pembimbing session, di-cache di redis
+// supaya tidak resolve user setiap kali ada event. hanya untuk fan-out broadcast, jangan dipakai untuk otorisasi
+func (ps *participantService) GetSessionParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error) {
+	cached, err := ps.participantRepo.GetSessionParticipants(ctx, sessionID)
+	if err != nil {
+		ps.logger.Warn("failed to get cached session participants",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+	}
+	if len(cached) > 0 {
+		return cached, nil
+	}
+
+	session, found, err := ps.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ps.logger.Error("failed get active session by session id",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetActiveSessionBySessionID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
+	var participantIDs []uuid.UUID
+	if session.Thesis.StudentID != uuid.Nil {
+		participantIDs = append(participantIDs, session.Thesis.StudentID)
+	}
+	for _, sup := range session.Thesis.Supervisors {
+		participantIDs = append(participantIDs, sup.LecturerID)
+	}
+
+	// resolve participant entity IDs (student/lecturer) -> user.id
+	userIDs := make([]string, 0, len(participantIDs))
+	for _, pid := range participantIDs {
+		user, found, err := ps.userRepo.GetUserByStudentOrLecturerID(ctx, nil, pid.String())
+		if err != nil || !found {
+			ps.logger.Warn("failed to resolve participant user",
+				zap.String("participant_entity_id", pid.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		userIDs = append(userIDs, user.ID.String())
+	}
+
+	if err := ps.participantRepo.SetSessionParticipants(ctx, sessionID, userIDs, sessionParticipantsTTL); err != nil {
+		ps.logger.Warn("failed to cache session participants",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+	}
+
+	return userIDs, nil
+}
+
+// IsSessionParticipant dipakai untuk gate subscribe room & sse, selalu cek ke db (bukan cache participant)
+// supaya pembimbing / mahasiswa yang dilepas dari thesis langsung kehilangan akses
+func (ps *participantService) IsSessionParticipant(ctx context.Context, sessionID, userID string) (bool, error) {
+	ok, err := ps.sessionRepo.IsSessionParticipant(ctx, nil, sessionID, userID)
+	if err != nil {
+		ps.logger.Error("failed check session participant",
+			zap.String("session_id", sessionID),
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return false, dto.ErrGetActiveSessionBySessionID
+	}
+
+	return ok, nil
+}
+
+// AuthorizeSession dipakai semua endpoint session / message / summary. session harus di-load
+// bersama Thesis.Supervisors (GetActiveSessionBySessionID / GetNoteSummaryBySessionID)
+func (ps *participantService) AuthorizeSession(ctx context.Context, session *entity.Session, user *entity.User) error {
+	if isThesisParticipant(&session.Thesis, user) {
+		return nil
+	}
+
+	ps.logger.Warn("non participant tried to access session",
+		zap.String("session_id", session.ID.String()),
+		zap.String("user_id", user.ID.String()),
+	)
+	return dto.ErrNotSessionParticipant
+}
+
+// AuthorizeThesis dipakai endpoint thesis / schedule, thesis harus di-load bersama Supervisors
+func (ps *participantService) AuthorizeThesis(ctx context.Context, thesis *entity.Thesis, user *entity.User) error {
+	if isThesisParticipant(thesis, user) {
+		return nil
+	}
+
+	ps.logger.Warn("non participant tried to access thesis",
+		zap.String("thesis_id", thesis.ID.String()),
+		zap.String("user_id", user.ID.String()),
+	)
+	return dto.ErrNotThesisParticipant
+}
+
+// isThesisParticipant user adalah mahasiswa pemilik thesis atau salah satu dosen pembimbingnya
+func isThesisParticipant(thesis *entity.Thesis, user *entity.User) bool {
+	if user.StudentID != nil && thesis.StudentID != uuid.Nil && *user.StudentID == thesis.StudentID {
+		return true
+	}
+	if user.LecturerID == nil {
+		return false
+	}
+	for _, sup := range thesis.Supervisors {
+		if sup.LecturerID == *user.LecturerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SubscribeSession mendaftarkan koneksi websocket pemanggil ke room session
+func (ps *participantService) SubscribeSession(ctx context.Context, sessionID string) error {
+	token := ctx.Value("Authorization").(string)
+	userID, err := ps.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return dto.ErrGetUserIDFromToken
+	}
+
+	ok, err := ps.IsSessionParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		ps.logger.Warn("non participant tried to subscribe session room",
+			zap.String("session_id", sessionID),
+			zap.String("user_id", userID),
+		)
+		return dto.ErrNotSessionParticipant
+	}
+
+	return ps.wsService.SubscribeRoom(ctx, SessionRoom(sessionID))
+}
+
+func (ps *participantService) UnsubscribeSession(ctx context.Context, sessionID string) error {
+	return ps.wsService.UnsubscribeRoom(ctx, SessionRoom(sessionID))
+}
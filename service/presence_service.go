@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// presenceTTL harus lebih lama dari interval ping websocket supaya koneksi sehat tidak sempat expired
+const presenceTTL = wsPongWait + 30*time.Second
+
+type (
+	IPresenceService interface {
+		WSConnectionListener
+		IsOnline(ctx context.Context, userID string) bool
+		GetThesisPresence(ctx context.Context, thesisID string) ([]dto.PresenceResponse, error)
+	}
+
+	presenceService struct {
+		presenceRepo       repository.IPresenceRepository
+		thesisRepo         repository.IThesisRepository
+		userRepo           repository.IUserRepository
+		logger             *zap.Logger
+		wsService          IWebsocketService
+		jwt                jwt.IJWT
+		participantService IParticipantService
+	}
+)
+
+func NewPresenceService(presenceRepo repository.IPresenceRepository, thesisRepo repository.IThesisRepository, userRepo repository.IUserRepository, logger *zap.Logger, wsService IWebsocketService, jwt jwt.IJWT, participantService IParticipantService) *presenceService {
+	return &presenceService{
+		presenceRepo:       presenceRepo,
+		thesisRepo:         thesisRepo,
+		userRepo:           userRepo,
+		logger:             logger,
+		wsService:          wsService,
+		jwt:                jwt,
+		participantService: participantService,
+	}
+}
+
+func (ps *presenceService) Connected(userID, connID string) {
+	ctx := context.Background()
+	cameOnline, err := ps.presenceRepo.AddConnection(ctx, userID, connID, presenceTTL)
+	if err != nil {
+		ps.logger.Error("failed to set user online",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if cameOnline {
+		ps.broadcast(ctx, userID, true, time.Now())
+	}
+}
+
+func (ps *presenceService) Heartbeat(userID, connID string) {
+	if err := ps.presenceRepo.RefreshConnection(context.Background(), userID, connID, presenceTTL); err != nil {
+		ps.logger.Warn("failed to refresh presence heartbeat",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (ps *presenceService) Disconnected(userID, connID string) {
+	ctx := context.Background()
+	wentOffline, err := ps.presenceRepo.RemoveConnection(ctx, userID, connID)
+	if err != nil {
+		ps.logger.Error("failed to set user offline",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	// offline hanya kalau device terakhir user (di semua instance) sudah disconnect
+	if wentOffline {
+		ps.broadcast(ctx, userID, false, time.Now())
+	}
+}
+
+func (ps *presenceService) IsOnline(ctx context.Context, userID string) bool {
+	online, _, err := ps.presenceRepo.GetPresence(ctx, userID)
+	if err != nil {
+		ps.logger.Warn("failed to get presence",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	return online
+}
+
+func (ps *presenceService) GetThesisPresence(ctx context.Context, thesisID string) ([]dto.PresenceResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ps.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ps.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ps.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
+	thesis, found, err := ps.thesisRepo.GetThesisByID(ctx, nil, thesisID)
+	if err != nil {
+		ps.logger.Error("failed to get thesis by id",
+			zap.String("thesis_id", thesisID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetThesisByID
+	}
+	if !found {
+		ps.logger.Warn("thesis not found",
+			zap.String("thesis_id", thesisID),
+		)
+		return nil, dto.ErrNotFound
+	}
+	if err := ps.participantService.AuthorizeThesis(ctx, thesis, user); err != nil {
+		return nil, err
+	}
+
+	var participantIDs []uuid.UUID
+	if thesis.StudentID != uuid.Nil {
+		participantIDs = append(participantIDs, thesis.StudentID)
+	}
+	for _, sup := range thesis.Supervisors {
+		participantIDs = append(participantIDs, sup.LecturerID)
+	}
+
+	// resolve participant entity IDs (student/lecturer) -> user.id
+	presences := make([]dto.PresenceResponse, 0, len(participantIDs))
+	for _, pid := range participantIDs {
+		user, found, err := ps.userRepo.GetUserByStudentOrLecturerID(ctx, nil, pid.String())
+		if err != nil || !found {
+			ps.logger.Warn("failed to resolve participant user",
+				zap.String("participant_entity_id", pid.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		online, lastSeen, err := ps.presenceRepo.GetPresence(ctx, user.ID.String())
+		if err != nil {
+			ps.logger.Error("failed to get presence",
+				zap.String("user_id", user.ID.String()),
+				zap.Error(err),
+			)
+			return nil, dto.ErrGetPresence
+		}
+
+		presences = append(presences, toPresenceResponse(user, online, lastSeen))
+	}
+	ps.logger.Info("success get thesis presence",
+		zap.String("thesis_id", thesisID),
+		zap.Int("count", len(presences)),
+	)
+
+	return presences, nil
+}
+
+// broadcast kirim presence_changed ke semua user yang satu thesis dengan user
+func (ps *presenceService) broadcast(ctx context.Context, userID string, online bool, at time.Time) {
+	user, found, err := ps.userRepo.GetUserByID(ctx, nil, userID)
+	if err != nil || !found {
+		ps.logger.Warn("failed to resolve user for presence event",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	receiverIDs, err := ps.thesisRepo.GetRelatedUserIDsByUser(ctx, nil, user)
+	if err != nil {
+		ps.logger.Error("failed to get related users for presence event",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	data, _ := json.Marshal(dto.PresenceEventPublish{
+		Event:    "presence_changed",
+		Presence: toPresenceResponse(user, online, &at),
+	})
+	for _, receiverID := range receiverIDs {
+		// receiver offline tidak perlu notifikasi presence
+		_ = ps.wsService.SendEphemeralToUser(receiverID, data)
+	}
+}
+
+func toPresenceResponse(user *entity.User, online bool, lastSeen *time.Time) dto.PresenceResponse {
+	res := dto.PresenceResponse{
+		UserID:   user.ID,
+		Role:     user.Role,
+		IsOnline: online,
+		LastSeen: lastSeen,
+	}
+	if user.LecturerID != nil {
+		res.Name = user.Lecturer.Name
+		res.Identifier = user.Lecturer.Nip
+	}
+	if user.StudentID != nil {
+		res.Name = user.Student.Name
+		res.Identifier = user.Student.Nim
+	}
+
+	return res
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Amierza/chat-service/constants"
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/repository"
+	"go.uber.org/zap"
+)
+
+// nama rule rate limit, dipakai middleware (per route) maupun service
+const (
+	RateLimitMessageSend    = "message_send"    // per user, POST messages
+	RateLimitSessionMessage = "session_message" // per session, semua sender digabung
+	RateLimitUpload         = "upload"          // per user, POST uploads
+)
+
+// defaultRateLimitRules key kosong berarti berlaku untuk semua role yang tidak punya aturan sendiri
+var defaultRateLimitRules = map[string]map[string]dto.RateLimitRule{
+	RateLimitMessageSend: {
+		"":                           {Capacity: 20, RefillPerSecond: 1},
+		constants.ENUM_ROLE_LECTURER: {Capacity: 30, RefillPerSecond: 2},
+	},
+	RateLimitSessionMessage: {
+		"": {Capacity: 60, RefillPerSecond: 5},
+	},
+	RateLimitUpload: {
+		"":                           {Capacity: 5, RefillPerSecond: 0.2},
+		constants.ENUM_ROLE_LECTURER: {Capacity: 10, RefillPerSecond: 0.5},
+	},
+}
+
+type (
+	IRateLimitService interface {
+		Allow(ctx context.Context, rule, subject, role string) (*dto.RateLimitResult, error)
+	}
+
+	rateLimitService struct {
+		rateLimitRepo repository.IRateLimitRepository
+		logger        *zap.Logger
+		rules         map[string]map[string]dto.RateLimitRule
+	}
+)
+
+// NewRateLimitService aturan default bisa di-override lewat env RATE_LIMIT_<RULE>[_<ROLE>]=<capacity>:<refill_per_second>,
+// contoh RATE_LIMIT_MESSAGE_SEND=20:1 atau RATE_LIMIT_UPLOAD_LECTURER=10:0.5
+func NewRateLimitService(rateLimitRepo repository.IRateLimitRepository, logger *zap.Logger) *rateLimitService {
+	rules := make(map[string]map[string]dto.RateLimitRule, len(defaultRateLimitRules))
+	for name, roles := range defaultRateLimitRules {
+		rules[name] = make(map[string]dto.RateLimitRule, len(roles))
+		for role, rule := range roles {
+			rules[name][role] = rule
+		}
+	}
+
+	roles := []string{
+		constants.ENUM_ROLE_STUDENT,
+		constants.ENUM_ROLE_LECTURER,
+		constants.ENUM_ROLE_PRIMARY_LECTURER,
+		constants.ENUM_ROLE_SECONDARY_LECTURER,
+	}
+	for name := range rules {
+		env := "RATE_LIMIT_" + strings.ToUpper(name)
+		if rule, ok := parseRateLimitRule(os.Getenv(env)); ok {
+			rules[name][""] = rule
+		}
+		for _, role := range roles {
+			if rule, ok := parseRateLimitRule(os.Getenv(env + "_" + strings.ToUpper(role))); ok {
+				rules[name][role] = rule
+			}
+		}
+	}
+
+	return &rateLimitService{
+		rateLimitRepo: rateLimitRepo,
+		logger:        logger,
+		rules:         rules,
+	}
+}
+
+func parseRateLimitRule(value string) (dto.RateLimitRule, bool) {
+	capacityStr, refillStr, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return dto.RateLimitRule{}, false
+	}
+	capacity, err := strconv.Atoi(capacityStr)
+	if err != nil || capacity <= 0 {
+		return dto.RateLimitRule{}, false
+	}
+	refill, err := strconv.ParseFloat(refillStr, 64)
+	if err != nil || refill <= 0 || math.IsInf(refill, 0) || math.IsNaN(refill) {
+		return dto.RateLimitRule{}, false
+	}
+
+	return dto.RateLimitRule{Capacity: capacity, RefillPerSecond: refill}, true
+}
+
+// Allow mengambil satu token dari bucket rule + subject (user id / session id). kalau bucket habis
+// result tetap dikembalikan bersama *dto.RateLimitError supaya caller bisa mengisi header.
+// redis gagal tidak memblokir request (fail open), hanya dicatat
+func (rs *rateLimitService) Allow(ctx context.Context, rule, subject, role string) (*dto.RateLimitResult, error) {
+	roles, ok := rs.rules[rule]
+	if !ok {
+		rs.logger.Warn("unknown rate limit rule",
+			zap.String("rule", rule),
+		)
+		return nil, nil
+	}
+	limit, ok := roles[role]
+	if !ok {
+		limit = roles[""]
+	}
+
+	result, err := rs.rateLimitRepo.TakeToken(ctx, rule+":"+subject, limit)
+	if err != nil {
+		rs.logger.Warn("failed to check rate limit",
+			zap.String("rule", rule),
+			zap.String("subject", subject),
+			zap.Error(err),
+		)
+		return nil, nil
+	}
+	if !result.Allowed {
+		rs.logger.Info("rate limit exceeded",
+			zap.String("rule", rule),
+			zap.String("subject", subject),
+			zap.String("role", role),
+			zap.Duration("retry_after", result.RetryAfter),
+		)
+		return result, &dto.RateLimitError{Result: *result}
+	}
+
+	return result, nil
+}
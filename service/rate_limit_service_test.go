@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Amierza/chat-service/dto"
+)
+
+func TestParseRateLimitRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   dto.RateLimitRule
+		wantOK bool
+	}{
+		{name: "integer refill", value: "20:1", want: dto.RateLimitRule{Capacity: 20, RefillPerSecond: 1}, wantOK: true},
+		{name: "fractional refill", value: "5:0.2", want: dto.RateLimitRule{Capacity: 5, RefillPerSecond: 0.2}, wantOK: true},
+		{name: "surrounding whitespace", value: " 10:0.5 ", want: dto.RateLimitRule{Capacity: 10, RefillPerSecond: 0.5}, wantOK: true},
+		{name: "empty", value: ""},
+		{name: "missing refill", value: "20"},
+		{name: "empty refill", value: "20:"},
+		{name: "empty capacity", value: ":1"},
+		{name: "zero capacity", value: "0:1"},
+		{name: "negative capacity", value: "-5:1"},
+		{name: "fractional capacity", value: "2.5:1"},
+		{name: "zero refill", value: "20:0"},
+		{name: "negative refill", value: "20:-1"},
+		{name: "nan refill", value: "20:NaN"},
+		{name: "infinite refill", value: "20:Inf"},
+		{name: "inner whitespace", value: "20 : 1"},
+		{name: "extra segment", value: "20:1:3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRateLimitRule(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRateLimitRule(%q) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("parseRateLimitRule(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
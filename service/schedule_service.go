@@ -25,19 +25,21 @@ type (
 	}
 
 	scheduleService struct {
-		scheduleRepo repository.IScheduleRepository
-		userRepo     repository.IUserRepository
-		logger       *zap.Logger
-		jwt          jwt.IJWT
+		scheduleRepo       repository.IScheduleRepository
+		userRepo           repository.IUserRepository
+		logger             *zap.Logger
+		jwt                jwt.IJWT
+		participantService IParticipantService
 	}
 )
 
-func NewScheduleService(scheduleRepo repository.IScheduleRepository, userRepo repository.IUserRepository, logger *zap.Logger, jwt jwt.IJWT) *scheduleService {
+func NewScheduleService(scheduleRepo repository.IScheduleRepository, userRepo repository.IUserRepository, logger *zap.Logger, jwt jwt.IJWT, participantService IParticipantService) *scheduleService {
 	return &scheduleService{
-		scheduleRepo: scheduleRepo,
-		userRepo:     userRepo,
-		logger:       logger,
-		jwt:          jwt,
+		scheduleRepo:       scheduleRepo,
+		userRepo:           userRepo,
+		logger:             logger,
+		jwt:                jwt,
+		participantService: participantService,
 	}
 }
 
@@ -64,6 +66,13 @@ func (ss *scheduleService) Create(ctx context.Context, req *dto.CreateScheduleRe
 		)
 		return nil, dto.ErrNotFound
 	}
+	// jadwal diajukan oleh mahasiswa untuk thesis miliknya
+	if user.StudentID == nil || len(user.Student.Theses) == 0 {
+		ss.logger.Warn("user has no thesis to create schedule",
+			zap.String("user_id", userIDString),
+		)
+		return nil, dto.ErrNotThesisParticipant
+	}
 
 	thesis, found, err := ss.scheduleRepo.GetThesisByID(ctx, nil, user.Student.Theses[0].ID.String())
 	if err != nil {
@@ -78,6 +87,9 @@ func (ss *scheduleService) Create(ctx context.Context, req *dto.CreateScheduleRe
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, thesis, user); err != nil {
+		return nil, err
+	}
 
 	newScheduleID := uuid.New()
 	newSchedule := entity.Schedule{
@@ -240,6 +252,28 @@ func (ss *scheduleService) GetAllWithPagination(ctx context.Context, req respons
 }
 
 func (ss *scheduleService) GetDetail(ctx context.Context, id *string) (*dto.ScheduleResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ss.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ss.logger.Error("failed to get user id string from token",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	user, found, err := ss.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ss.logger.Error("failed to get user from user id string",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	if !found {
+		ss.logger.Warn("user not found",
+			zap.String("user_id", userIDString),
+		)
+		return nil, dto.ErrNotFound
+	}
+
 	data, found, err := ss.scheduleRepo.GetScheduleByID(ctx, nil, id)
 	if err != nil {
 		ss.logger.Error("failed to get schedule by id",
@@ -254,6 +288,9 @@ func (ss *scheduleService) GetDetail(ctx context.Context, id *string) (*dto.Sche
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, &data.Thesis, user); err != nil {
+		return nil, err
+	}
 
 	schedule := dto.ScheduleResponse{
 		ID:          data.ID,
@@ -346,6 +383,9 @@ func (ss *scheduleService) Update(ctx context.Context, req *dto.UpdateScheduleRe
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, &schedule.Thesis, user); err != nil {
+		return nil, err
+	}
 
 	_, found, err = ss.scheduleRepo.GetThesisByID(ctx, nil, schedule.Thesis.ID.String())
 	if err != nil {
@@ -419,7 +459,7 @@ func (ss *scheduleService) Update(ctx context.Context, req *dto.UpdateScheduleRe
 }
 
 func (ss *scheduleService) Approval(ctx context.Context, req *dto.ApprovalScheduleRequest) error {
-	_, found, err := ss.scheduleRepo.GetScheduleByID(ctx, nil, &req.ID)
+	schedule, found, err := ss.scheduleRepo.GetScheduleByID(ctx, nil, &req.ID)
 	if err != nil {
 		ss.logger.Error("failed to get schedule by id",
 			zap.String("id", req.ID),
@@ -455,12 +495,15 @@ func (ss *scheduleService) Approval(ctx context.Context, req *dto.ApprovalSchedu
 		)
 		return dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, &schedule.Thesis, user); err != nil {
+		return err
+	}
 
 	if user.Role == constants.ENUM_ROLE_STUDENT {
 		ss.logger.Warn("student cannot approval",
 			zap.String("user_id", userIDString),
 		)
-		return fmt.Errorf("access denied")
+		return dto.ErrStudentCannotApproveSchedule
 	}
 
 	newStatus := req.Status
@@ -490,7 +533,29 @@ func (ss *scheduleService) Approval(ctx context.Context, req *dto.ApprovalSchedu
 }
 
 func (ss *scheduleService) Delete(ctx context.Context, id *string) error {
-	_, found, err := ss.scheduleRepo.GetScheduleByID(ctx, nil, id)
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ss.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ss.logger.Error("failed to get user id string from token",
+			zap.Error(err),
+		)
+		return err
+	}
+	user, found, err := ss.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ss.logger.Error("failed to get user from user id string",
+			zap.Error(err),
+		)
+		return err
+	}
+	if !found {
+		ss.logger.Warn("user not found",
+			zap.String("user_id", userIDString),
+		)
+		return dto.ErrNotFound
+	}
+
+	schedule, found, err := ss.scheduleRepo.GetScheduleByID(ctx, nil, id)
 	if err != nil {
 		ss.logger.Error("failed to get schedule by id before delete",
 			zap.String("id", *id),
@@ -504,6 +569,9 @@ func (ss *scheduleService) Delete(ctx context.Context, id *string) error {
 		)
 		return dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, &schedule.Thesis, user); err != nil {
+		return err
+	}
 
 	// delete schedule
 	err = ss.scheduleRepo.DeleteScheduleByID(ctx, nil, id)
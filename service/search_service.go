@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/Amierza/chat-service/response"
+	"go.uber.org/zap"
+)
+
+const (
+	searchContextSize = 2
+	searchMaxPerPage  = 50
+)
+
+type (
+	ISearchService interface {
+		SearchMessages(ctx context.Context, req dto.MessageSearchRequest) (*dto.MessageSearchPaginationResponse, error)
+	}
+
+	searchService struct {
+		messageRepo repository.IMessageRepository
+		sessionRepo repository.ISessionRepository
+		userRepo    repository.IUserRepository
+		logger      *zap.Logger
+		jwt         jwt.IJWT
+	}
+)
+
+func NewSearchService(messageRepo repository.IMessageRepository, sessionRepo repository.ISessionRepository, userRepo repository.IUserRepository, logger *zap.Logger, jwt jwt.IJWT) *searchService {
+	return &searchService{
+		messageRepo: messageRepo,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		logger:      logger,
+		jwt:         jwt,
+	}
+}
+
+// SearchMessages hasil dari session live (redis) ditampilkan lebih dulu, dilanjutkan hasil
+// full-text search postgres untuk session yang sudah selesai
+func (ss *searchService) SearchMessages(ctx context.Context, req dto.MessageSearchRequest) (*dto.MessageSearchPaginationResponse, error) {
+	// get information user login
+	token := ctx.Value("Authorization").(string)
+	userID, err := ss.jwt.GetUserIDByToken(token)
+	if err != nil {
+		ss.logger.Error("failed to extract user_id from token",
+			zap.String("access_token", token),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ss.userRepo.GetUserByID(ctx, nil, userID)
+	if err != nil {
+		ss.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
+	if req.PerPage <= 0 {
+		req.PerPage = 10
+	}
+	if req.PerPage > searchMaxPerPage {
+		req.PerPage = searchMaxPerPage
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	offset := req.GetOffset()
+
+	live, err := ss.searchLive(ctx, user, req.Query)
+	if err != nil {
+		return nil, dto.ErrSearchMessages
+	}
+
+	datas := make([]dto.MessageSearchResponse, 0, req.PerPage)
+	if offset < len(live) {
+		end := offset + req.PerPage
+		if end > len(live) {
+			end = len(live)
+		}
+		datas = append(datas, live[offset:end]...)
+	}
+
+	// sisa halaman diisi dari postgres, offset digeser sebanyak hasil live
+	dbOffset := offset - len(live)
+	if dbOffset < 0 {
+		dbOffset = 0
+	}
+	rows, count, err := ss.messageRepo.SearchMessages(ctx, nil, user, req.Query, dbOffset, req.PerPage-len(datas))
+	if err != nil {
+		ss.logger.Error("failed to search messages",
+			zap.String("user_id", userID),
+			zap.String("query", req.Query),
+			zap.Error(err),
+		)
+		return nil, dto.ErrSearchMessages
+	}
+
+	for _, row := range rows {
+		data := dto.MessageSearchResponse{
+			MessageID: row.MessageID,
+			Seq:       row.Seq,
+			SessionID: row.SessionID,
+			ThesisID:  row.ThesisID,
+			Sender: dto.CustomUserResponse{
+				ID:         row.SenderID,
+				Name:       row.SenderName,
+				Identifier: row.SenderIdentifier,
+				Role:       row.SenderRole,
+			},
+			Text:      row.Text,
+			Highlight: row.Highlight,
+			Timestamp: row.CreatedAt.Format(time.RFC3339Nano),
+		}
+
+		before, after, err := ss.messageRepo.GetMessageContext(ctx, nil, row.SessionID, row.Seq, searchContextSize)
+		if err != nil {
+			ss.logger.Warn("failed to get message context",
+				zap.String("message_id", row.MessageID.String()),
+				zap.Error(err),
+			)
+		}
+		data.Context.Before = make([]dto.MessageResponse, 0, len(before))
+		for _, message := range before {
+			data.Context.Before = append(data.Context.Before, toMessageResponse(message))
+		}
+		data.Context.After = make([]dto.MessageResponse, 0, len(after))
+		for _, message := range after {
+			data.Context.After = append(data.Context.After, toMessageResponse(message))
+		}
+
+		datas = append(datas, data)
+	}
+
+	total := count + int64(len(live))
+	ss.logger.Info("success search messages",
+		zap.String("user_id", userID),
+		zap.Int("live", len(live)),
+		zap.Int64("total", total),
+	)
+
+	return &dto.MessageSearchPaginationResponse{
+		Data: datas,
+		PaginationResponse: response.PaginationResponse{
+			Page:    req.Page,
+			PerPage: req.PerPage,
+			MaxPage: int64(math.Ceil(float64(total) / float64(req.PerPage))),
+			Count:   total,
+		},
+	}, nil
+}
+
+// searchLive mencari di message redis session ongoing / processing_summary, terbaru lebih dulu
+func (ss *searchService) searchLive(ctx context.Context, user *entity.User, query string) ([]dto.MessageSearchResponse, error) {
+	include, exclude := searchTerms(query)
+	if len(include) == 0 {
+		return nil, nil
+	}
+
+	sessions, err := ss.sessionRepo.GetLiveSessionsByUser(ctx, nil, user)
+	if err != nil {
+		ss.logger.Error("failed to get live sessions",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	quoted := make([]string, 0, len(include))
+	for _, term := range include {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	highlighter := regexp.MustCompile(`(?i)(` + strings.Join(quoted, "|") + `)`)
+
+	var hits []dto.MessageSearchResponse
+	for _, session := range sessions {
+		messages, err := ss.messageRepo.GetAllMessageFromRedis(ctx, nil, session)
+		if err != nil {
+			ss.logger.Warn("failed to get live messages",
+				zap.String("session_id", session.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		list := *messages
+		for i, evt := range list {
+			if evt.IsDeleted || !matchTerms(evt.Text, include, exclude) {
+				continue
+			}
+
+			hit := dto.MessageSearchResponse{
+				MessageID: evt.MessageID,
+				Seq:       evt.Seq,
+				SessionID: session.ID,
+				ThesisID:  session.ThesisID,
+				Sender:    evt.Sender,
+				Text:      evt.Text,
+				Highlight: highlighter.ReplaceAllString(evt.Text, "<mark>$1</mark>"),
+				IsLive:    true,
+				Timestamp: evt.Timestamp,
+			}
+			hit.Context.Before = liveContext(list, i-searchContextSize, i)
+			hit.Context.After = liveContext(list, i+1, i+1+searchContextSize)
+
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, hits[i].Timestamp)
+		tj, _ := time.Parse(time.RFC3339Nano, hits[j].Timestamp)
+		return ti.After(tj)
+	})
+
+	return hits, nil
+}
+
+// searchTerms memecah query gaya websearch: "frasa" jadi satu term, -kata berarti dikecualikan
+func searchTerms(query string) ([]string, []string) {
+	var include, exclude []string
+	for i, part := range strings.Split(query, `"`) {
+		words := []string{part}
+		if i%2 == 0 {
+			words = strings.Fields(part)
+		}
+		for _, word := range words {
+			word = strings.ToLower(strings.TrimSpace(word))
+			switch {
+			case word == "" || word == "or":
+			case strings.HasPrefix(word, "-") && len(word) > 1:
+				exclude = append(exclude, word[1:])
+			default:
+				include = append(include, word)
+			}
+		}
+	}
+
+	return include, exclude
+}
+
+func matchTerms(text string, include, exclude []string) bool {
+	text = strings.ToLower(text)
+	for _, term := range exclude {
+		if strings.Contains(text, term) {
+			return false
+		}
+	}
+	for _, term := range include {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func liveContext(list []dto.MessageEventPublish, from, to int) []dto.MessageResponse {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(list) {
+		to = len(list)
+	}
+
+	res := make([]dto.MessageResponse, 0, searchContextSize)
+	for _, evt := range list[from:to] {
+		if evt.IsDeleted {
+			continue
+		}
+		res = append(res, dto.MessageResponse{
+			ID:              evt.MessageID,
+			Seq:             evt.Seq,
+			IsText:          evt.IsText,
+			Text:            evt.Text,
+			FileURL:         evt.FileURL,
+			Sender:          evt.Sender,
+			ParentMessageID: evt.ParentMessageID,
+			Mentions:        evt.Mentions,
+			IsEdited:        evt.IsEdited,
+			EditedAt:        evt.EditedAt,
+			Timestamp:       evt.Timestamp,
+		})
+	}
+
+	return res
+}
+
+func toMessageResponse(message entity.Message) dto.MessageResponse {
+	res := dto.MessageResponse{
+		ID:      message.ID,
+		Seq:     message.Seq,
+		IsText:  &message.IsText,
+		Text:    message.Text,
+		FileURL: message.FileURL,
+		Sender: dto.CustomUserResponse{
+			ID:   message.Sender.ID,
+			Role: string(message.Sender.Role),
+		},
+		ParentMessageID: message.ParentMessageID,
+		Mentions:        message.Mentions,
+		IsEdited:        message.IsEdited,
+		IsDeleted:       message.IsDeleted,
+		Timestamp:       message.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if message.EditedAt != nil {
+		res.EditedAt = message.EditedAt.Format(time.RFC3339Nano)
+	}
+	if message.Sender.LecturerID != nil {
+		res.Sender.Name = message.Sender.Lecturer.Name
+		res.Sender.Identifier = message.Sender.Lecturer.Nip
+	}
+	if message.Sender.StudentID != nil {
+		res.Sender.Name = message.Sender.Student.Name
+		res.Sender.Identifier = message.Sender.Student.Nim
+	}
+
+	return res
+}
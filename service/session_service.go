@@ -15,9 +15,9 @@ import (
 	"github.com/Amierza/chat-service/repository"
 	"github.com/Amierza/chat-service/response"
 	"github.com/google/uuid"
-	"github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type (
@@ -33,29 +33,33 @@ type (
 	}
 
 	sessionService struct {
-		sessionRepo      repository.ISessionRepository
-		messageRepo      repository.IMessageRepository
-		notificationRepo repository.INotificationRepository
-		userRepo         repository.IUserRepository
-		logger           *zap.Logger
-		rabbitmq         *amqp091.Connection
-		wsService        IWebsocketService
-		jwt              jwt.IJWT
-		redis            *redis.Client
+		sessionRepo        repository.ISessionRepository
+		messageRepo        repository.IMessageRepository
+		notificationRepo   repository.INotificationRepository
+		userRepo           repository.IUserRepository
+		logger             *zap.Logger
+		outboxRepo         repository.IOutboxRepository
+		wsService          IWebsocketService
+		messagePersister   IMessagePersisterService
+		jwt                jwt.IJWT
+		redis              *redis.Client
+		participantService IParticipantService
 	}
 )
 
-func NewSessionService(sessionRepo repository.ISessionRepository, messageRepo repository.IMessageRepository, notificationRepo repository.INotificationRepository, userRepo repository.IUserRepository, logger *zap.Logger, rabbitmq *amqp091.Connection, wsService IWebsocketService, jwt jwt.IJWT, redis *redis.Client) *sessionService {
+func NewSessionService(sessionRepo repository.ISessionRepository, messageRepo repository.IMessageRepository, notificationRepo repository.INotificationRepository, userRepo repository.IUserRepository, logger *zap.Logger, outboxRepo repository.IOutboxRepository, wsService IWebsocketService, messagePersister IMessagePersisterService, jwt jwt.IJWT, redis *redis.Client, participantService IParticipantService) *sessionService {
 	return &sessionService{
-		sessionRepo:      sessionRepo,
-		messageRepo:      messageRepo,
-		notificationRepo: notificationRepo,
-		userRepo:         userRepo,
-		logger:           logger,
-		rabbitmq:         rabbitmq,
-		wsService:        wsService,
-		jwt:              jwt,
-		redis:            redis,
+		sessionRepo:        sessionRepo,
+		messageRepo:        messageRepo,
+		notificationRepo:   notificationRepo,
+		userRepo:           userRepo,
+		logger:             logger,
+		outboxRepo:         outboxRepo,
+		wsService:          wsService,
+		messagePersister:   messagePersister,
+		jwt:                jwt,
+		redis:              redis,
+		participantService: participantService,
 	}
 }
 
@@ -139,17 +143,6 @@ func (ss *sessionService) Start(ctx context.Context, thesisID string) (*dto.Sess
 		return &dto.SessionResponse{}, dto.ErrNotFound
 	}
 
-	// handle existing session
-	existing, found, _ := ss.sessionRepo.GetActiveSessionByThesisID(ctx, nil, thesisID)
-	if existing != nil && found {
-		ss.logger.Info("active session already exists",
-			zap.String("thesis_id", thesisID),
-			zap.String("session_id", existing.ID.String()),
-		)
-
-		return nil, dto.ErrSessionAlreadyStarted
-	}
-
 	// get thesis for prepare start session
 	thesis, found, err := ss.sessionRepo.GetThesisByID(ctx, nil, thesisID)
 	if err != nil {
@@ -165,6 +158,20 @@ func (ss *sessionService) Start(ctx context.Context, thesisID string) (*dto.Sess
 		)
 		return &dto.SessionResponse{}, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeThesis(ctx, thesis, user); err != nil {
+		return &dto.SessionResponse{}, err
+	}
+
+	// handle existing session
+	existing, found, _ := ss.sessionRepo.GetActiveSessionByThesisID(ctx, nil, thesisID)
+	if existing != nil && found {
+		ss.logger.Info("active session already exists",
+			zap.String("thesis_id", thesisID),
+			zap.String("session_id", existing.ID.String()),
+		)
+
+		return nil, dto.ErrSessionAlreadyStarted
+	}
 	tID, err := uuid.Parse(thesisID)
 	if err != nil {
 		ss.logger.Error("failed to parse thesis_id to uuid",
@@ -385,6 +392,9 @@ func (ss *sessionService) Join(ctx context.Context, sessionID string) (*dto.Sess
 		)
 		return &dto.SessionResponse{}, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return &dto.SessionResponse{}, err
+	}
 
 	// cannot join if session is already process messages for summary
 	if session.Status == constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY {
@@ -446,7 +456,7 @@ func (ss *sessionService) Join(ctx context.Context, sessionID string) (*dto.Sess
 			}
 		}
 	} else {
-		return nil, dto.ErrUnauthorized
+		return nil, dto.ErrNotSessionParticipant
 	}
 
 	// resolve receiver entity IDs (student/lecturer) -> user.id
@@ -622,6 +632,9 @@ func (ss *sessionService) Leave(ctx context.Context, sessionID string) (*dto.Ses
 		)
 		return &dto.SessionResponse{}, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return &dto.SessionResponse{}, err
+	}
 
 	// cannot leave if session is not ongoing
 	if session.Status == constants.ENUM_SESSION_STATUS_WAITING {
@@ -678,7 +691,7 @@ func (ss *sessionService) Leave(ctx context.Context, sessionID string) (*dto.Ses
 			}
 		}
 	} else {
-		return nil, dto.ErrUnauthorized
+		return nil, dto.ErrNotSessionParticipant
 	}
 
 	// resolve receiver entity IDs (student/lecturer) -> user.id
@@ -830,6 +843,9 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 		)
 		return &dto.SessionResponse{}, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeSession(ctx, session, user); err != nil {
+		return &dto.SessionResponse{}, err
+	}
 
 	// only can end if still ongoing
 	if session.Status == constants.ENUM_SESSION_STATUS_WAITING {
@@ -868,20 +884,71 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 	now := time.Now()
 	session.Status = constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY
 	session.EndTime = &now
-	// update session -> join & start session -> status = ongoing
-	if err := ss.sessionRepo.UpdateSession(ctx, nil, session); err != nil {
-		ss.logger.Error("failed to update session to finished",
-			zap.String("session_id", sessionID),
-			zap.Error(err),
-		)
-		return &dto.SessionResponse{}, dto.ErrUpdateSession
+
+	messages, err := ss.messageRepo.GetAllMessageFromRedis(ctx, nil, session)
+	if err != nil {
+		ss.logger.Error("failed to get messages from redis", zap.Error(err))
+		return nil, dto.ErrGetAllMessageWithPagination
+	}
+
+	pins, err := ss.messageRepo.GetMessagePinsBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		ss.logger.Error("failed to get message pins", zap.Error(err))
+		return nil, dto.ErrGetPinnedMessages
+	}
+
+	data, err := json.Marshal(ss.buildSummaryTask(session, user, *messages, pins))
+	if err != nil {
+		ss.logger.Error("failed marshal summary task", zap.Error(err))
+		return nil, dto.ErrMarshalToJSON
+	}
+
+	// status session & summary task ditulis dalam satu transaksi, publish ke rabbitmq dikerjakan outbox relay
+	err = ss.sessionRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if err := ss.sessionRepo.UpdateSession(ctx, tx, session); err != nil {
+			ss.logger.Error("failed to update session to processing summary",
+				zap.String("session_id", sessionID),
+				zap.Error(err),
+			)
+			return dto.ErrUpdateSession
+		}
+
+		outbox := &entity.Outbox{
+			ID:            uuid.New(),
+			Queue:         constants.ENUM_QUEUE_SUMMARY_TASK,
+			Payload:       string(data),
+			Status:        constants.ENUM_OUTBOX_STATUS_PENDING,
+			NextAttemptAt: now,
+			AggregateID:   session.ID,
+		}
+		if err := ss.outboxRepo.CreateOutbox(ctx, tx, outbox); err != nil {
+			ss.logger.Error("failed to create summary task outbox",
+				zap.String("session_id", sessionID),
+				zap.Error(err),
+			)
+			return dto.ErrCreateOutbox
+		}
+
+		return nil
+	})
+	if err != nil {
+		return &dto.SessionResponse{}, err
 	}
-	ss.logger.Info("session ended successfully, status updated to finished",
+	ss.logger.Info("session ended successfully, summary task queued in outbox",
 		zap.String("session_id", sessionID),
 		zap.String("thesis_id", session.ThesisID.String()),
 		zap.Time("end_time", now),
+		zap.Int("messages_count", len(*messages)),
 	)
 
+	// final flush chat redis ke postgres, kalau gagal akan diulang oleh persister
+	if _, err := ss.messagePersister.Flush(ctx, sessionID); err != nil {
+		ss.logger.Error("failed to flush session messages to database",
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+	}
+
 	var receiverIDs []uuid.UUID
 	ender := ""
 	if user.StudentID != nil && session.Thesis.StudentID != uuid.Nil && *user.StudentID == session.Thesis.StudentID {
@@ -900,7 +967,7 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 			}
 		}
 	} else {
-		return nil, dto.ErrUnauthorized
+		return nil, dto.ErrNotSessionParticipant
 	}
 
 	// resolve receiver entity IDs (student/lecturer) -> user.id
@@ -962,34 +1029,42 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 		}
 	}
 
-	messages, err := ss.messageRepo.GetAllMessageFromRedis(ctx, nil, session)
-	if err != nil {
-		ss.logger.Error("failed to get messages from redis", zap.Error(err))
-		return nil, dto.ErrGetAllMessageWithPagination
+	res := &dto.SessionResponse{
+		ID:        session.ID,
+		StartTime: session.StartTime,
+		EndTime:   session.EndTime,
+		Status:    session.Status,
+		Thesis: dto.ThesisResponse{
+			ID:          session.ThesisID,
+			Title:       session.Thesis.Title,
+			Description: session.Thesis.Description,
+			Progress:    session.Thesis.Progress,
+			Student: &dto.CustomUserResponse{
+				ID:         session.Thesis.Student.ID,
+				Name:       session.Thesis.Student.Name,
+				Identifier: session.Thesis.Student.Nim,
+			},
+		},
+		UserOwner: dto.UserResponse{
+			ID:         session.UserOwner.ID,
+			Identifier: session.UserOwner.Identifier,
+			Role:       session.UserOwner.Role,
+			Student:    mapStudent(session.UserOwner),
+			Lecturer:   mapLecturer(session.UserOwner),
+		},
 	}
-
-	ch, err := ss.rabbitmq.Channel()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+	for _, sup := range session.Thesis.Supervisors {
+		res.Thesis.Supervisors = append(res.Thesis.Supervisors, &dto.CustomUserResponse{
+			ID:         sup.LecturerID,
+			Name:       sup.Lecturer.Name,
+			Identifier: sup.Lecturer.Nip,
+		})
 	}
-	defer ch.Close()
-
-	queueName := "summary_task"
 
-	// Declare queue (pastikan queue ada)
-	_, err = ch.QueueDeclare(
-		queueName,
-		true,  // durable
-		false, // autoDelete
-		false, // exclusive
-		false, // noWait
-		nil,   // args
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
-	}
+	return res, nil
+}
 
-	// Bungkus ke task
+func (ss *sessionService) buildSummaryTask(session *entity.Session, user *entity.User, messages []dto.MessageEventPublish, pins []entity.MessagePin) dto.TaskSummary {
 	task := dto.TaskSummary{
 		SessionID:     session.ID,
 		SessionStatus: string(session.Status),
@@ -1052,7 +1127,13 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 		task.Supervisors = append(task.Supervisors, data)
 	}
 
-	for _, msg := range *messages {
+	summaries := make(map[uuid.UUID]dto.MessageSummary, len(messages))
+	for _, msg := range messages {
+		// message yang sudah dihapus pengirim tidak ikut diringkas
+		if msg.IsDeleted {
+			continue
+		}
+
 		data := dto.MessageSummary{
 			ID:      msg.MessageID,
 			IsText:  *msg.IsText,
@@ -1069,73 +1150,24 @@ func (ss *sessionService) End(ctx context.Context, sessionID string) (*dto.Sessi
 		}
 
 		task.Messages = append(task.Messages, data)
+		summaries[data.ID] = data
 	}
 
-	data, err := json.Marshal(task)
-	if err != nil {
-		ss.logger.Error("failed marshal summary task", zap.Error(err))
-		return nil, dto.ErrMarshalToJSON
-	}
-
-	ct, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	// Publish message
-	err = ch.PublishWithContext(
-		ct,
-		"",        // exchange
-		queueName, // routing key
-		false,     // mandatory
-		false,     // immediate
-		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        data,
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to publish message: %w", err)
-	}
-
-	log.Printf("✅ published message to %s", queueName)
-
-	ss.logger.Info("success publish summary task to rabbitmq",
-		zap.String("session_id", sessionID),
-		zap.Int("messages_count", len(*messages)),
-	)
+	// pin mengikuti urutan waktu pin, message yang sudah dihapus ikut terlewati
+	for _, pin := range pins {
+		data, ok := summaries[pin.MessageID]
+		if !ok {
+			continue
+		}
 
-	res := &dto.SessionResponse{
-		ID:        session.ID,
-		StartTime: session.StartTime,
-		EndTime:   session.EndTime,
-		Status:    session.Status,
-		Thesis: dto.ThesisResponse{
-			ID:          session.ThesisID,
-			Title:       session.Thesis.Title,
-			Description: session.Thesis.Description,
-			Progress:    session.Thesis.Progress,
-			Student: &dto.CustomUserResponse{
-				ID:         session.Thesis.Student.ID,
-				Name:       session.Thesis.Student.Name,
-				Identifier: session.Thesis.Student.Nim,
-			},
-		},
-		UserOwner: dto.UserResponse{
-			ID:         session.UserOwner.ID,
-			Identifier: session.UserOwner.Identifier,
-			Role:       session.UserOwner.Role,
-			Student:    mapStudent(session.UserOwner),
-			Lecturer:   mapLecturer(session.UserOwner),
-		},
-	}
-	for _, sup := range session.Thesis.Supervisors {
-		res.Thesis.Supervisors = append(res.Thesis.Supervisors, &dto.CustomUserResponse{
-			ID:         sup.LecturerID,
-			Name:       sup.Lecturer.Name,
-			Identifier: sup.Lecturer.Nip,
+		task.Pinned = append(task.Pinned, dto.PinnedMessageSummary{
+			MessageSummary: data,
+			PinnedBy:       toCustomUserResponse(&pin.PinnedBy),
+			PinnedAt:       pin.CreatedAt.Format(time.RFC3339Nano),
 		})
 	}
 
-	return res, nil
+	return task
 }
 
 func (ss *sessionService) GetAll(ctx context.Context, filter dto.SessionFilterQuery) ([]*dto.SessionResponse, error) {
@@ -1167,6 +1199,7 @@ func (ss *sessionService) GetAll(ctx context.Context, filter dto.SessionFilterQu
 		return nil, dto.ErrGetAllSessionsByUserID
 	}
 
+	unread := ss.unreadCounts(ctx, datas, userIDString)
 	sessions := make([]*dto.SessionResponse, 0, len(datas))
 	for _, data := range datas {
 		session := &dto.SessionResponse{
@@ -1201,6 +1234,7 @@ func (ss *sessionService) GetAll(ctx context.Context, filter dto.SessionFilterQu
 				Identifier: sup.Lecturer.Nip,
 			})
 		}
+		session.UnreadCount = unread[data.ID.String()]
 
 		sessions = append(sessions, session)
 	}
@@ -1240,6 +1274,7 @@ func (ss *sessionService) GetAllWithPagination(ctx context.Context, req response
 		return dto.SessionPaginationResponse{}, dto.ErrGetAllSessionsByUserIDWithPagination
 	}
 
+	unread := ss.unreadCounts(ctx, datas.Sessions, userIDString)
 	sessions := make([]*dto.SessionResponse, 0, len(datas.Sessions))
 	for _, data := range datas.Sessions {
 		session := &dto.SessionResponse{
@@ -1274,6 +1309,7 @@ func (ss *sessionService) GetAllWithPagination(ctx context.Context, req response
 				Identifier: sup.Lecturer.Nip,
 			})
 		}
+		session.UnreadCount = unread[data.ID.String()]
 
 		sessions = append(sessions, session)
 	}
@@ -1293,6 +1329,23 @@ func (ss *sessionService) GetAllWithPagination(ctx context.Context, req response
 }
 
 func (ss *sessionService) GetDetail(ctx context.Context, id *string) (*dto.SessionResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ss.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ss.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ss.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
 	data, found, err := ss.sessionRepo.GetActiveSessionBySessionID(ctx, nil, *id)
 	if err != nil {
 		ss.logger.Error("failed to get session by id",
@@ -1307,6 +1360,9 @@ func (ss *sessionService) GetDetail(ctx context.Context, id *string) (*dto.Sessi
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeSession(ctx, data, user); err != nil {
+		return nil, err
+	}
 
 	session := &dto.SessionResponse{
 		ID:        data.ID,
@@ -1340,6 +1396,7 @@ func (ss *sessionService) GetDetail(ctx context.Context, id *string) (*dto.Sessi
 			Identifier: sup.Lecturer.Nip,
 		})
 	}
+	session.UnreadCount = ss.unreadCounts(ctx, []*entity.Session{data}, userIDString)[data.ID.String()]
 	ss.logger.Info("success get detail session",
 		zap.String("id", *id),
 	)
@@ -1348,6 +1405,23 @@ func (ss *sessionService) GetDetail(ctx context.Context, id *string) (*dto.Sessi
 }
 
 func (ss *sessionService) GetSummary(ctx context.Context, id *string) (*dto.NoteSummaryResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := ss.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := ss.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		ss.logger.Error("failed to fetch user by id",
+			zap.String("user_id", userIDString),
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
 	data, found, err := ss.sessionRepo.GetNoteSummaryBySessionID(ctx, nil, *id)
 	if err != nil {
 		ss.logger.Error("failed to get summary by session id",
@@ -1363,6 +1437,9 @@ func (ss *sessionService) GetSummary(ctx context.Context, id *string) (*dto.Note
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ss.participantService.AuthorizeSession(ctx, &data.Session, user); err != nil {
+		return nil, err
+	}
 
 	note := &dto.NoteSummaryResponse{
 		ID:      data.ID,
@@ -1380,3 +1457,61 @@ func (ss *sessionService) GetSummary(ctx context.Context, id *string) (*dto.Note
 
 	return note, nil
 }
+
+// unreadCounts menghitung message dari participant lain setelah read cursor user untuk banyak session
+// sekaligus: satu query grouped untuk session selesai, satu query cursor + satu pipeline redis untuk session live
+func (ss *sessionService) unreadCounts(ctx context.Context, sessions []*entity.Session, userID string) map[string]int64 {
+	var finished, live []string
+	for _, session := range sessions {
+		switch session.Status {
+		case constants.ENUM_SESSION_STATUS_ONGOING,
+			constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY:
+			live = append(live, session.ID.String())
+		case constants.ENUM_SESSION_STATUS_FINSIHED:
+			finished = append(finished, session.ID.String())
+		}
+	}
+
+	res, err := ss.messageRepo.CountUnreadMessages(ctx, nil, userID, finished)
+	if err != nil {
+		ss.logger.Warn("failed to count unread messages",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		res = make(map[string]int64, len(sessions))
+	}
+	if len(live) == 0 {
+		return res
+	}
+
+	// belum pernah mark read berarti semua message dihitung belum dibaca
+	cursors, err := ss.messageRepo.GetReadCursorsBySessionIDs(ctx, nil, userID, live)
+	if err != nil {
+		ss.logger.Warn("failed to get read cursors",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return res
+	}
+	afters := make(map[string]int64, len(live))
+	for _, sessionID := range live {
+		afters[sessionID] = 0
+	}
+	for _, cursor := range cursors {
+		afters[cursor.SessionID.String()] = cursor.LastReadSeq
+	}
+
+	counts, err := ss.messageRepo.CountUnreadMessagesFromRedis(ctx, nil, userID, afters)
+	if err != nil {
+		ss.logger.Warn("failed to count unread messages from redis",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return res
+	}
+	for sessionID, count := range counts {
+		res[sessionID] = count
+	}
+
+	return res
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
+	"go.uber.org/zap"
+)
+
+// streamTicketTTL cukup untuk membuka EventSource setelah ticket didapat
+const streamTicketTTL = 30 * time.Second
+
+type (
+	IStreamTicketService interface {
+		IssueTicket(ctx context.Context) (*dto.StreamTicketResponse, error)
+		RedeemTicket(ctx context.Context, ticket string) (string, string, error)
+	}
+
+	streamTicketService struct {
+		streamTicketRepo repository.IStreamTicketRepository
+		logger           *zap.Logger
+		jwt              jwt.IJWT
+	}
+)
+
+func NewStreamTicketService(streamTicketRepo repository.IStreamTicketRepository, logger *zap.Logger, jwt jwt.IJWT) *streamTicketService {
+	return &streamTicketService{
+		streamTicketRepo: streamTicketRepo,
+		logger:           logger,
+		jwt:              jwt,
+	}
+}
+
+// IssueTicket EventSource di browser tidak bisa set header Authorization, jadi client menukar jwt dengan
+// ticket sekali pakai yang berumur pendek lalu membuka stream dengan ?ticket=
+func (sts *streamTicketService) IssueTicket(ctx context.Context) (*dto.StreamTicketResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	expiresAt, err := sts.jwt.GetExpiryByToken(token)
+	if err != nil {
+		sts.logger.Error("failed get expiry from token",
+			zap.Error(err),
+		)
+		return nil, dto.ErrValidateToken
+	}
+
+	// ticket tidak boleh hidup lebih lama dari token yang ditukar
+	ttl := streamTicketTTL
+	if remaining := time.Until(expiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	if ttl <= 0 {
+		return nil, dto.ErrValidateToken
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		sts.logger.Error("failed to generate stream ticket",
+			zap.Error(err),
+		)
+		return nil, dto.ErrCreateStreamTicket
+	}
+	ticket := hex.EncodeToString(buf)
+
+	if err := sts.streamTicketRepo.CreateStreamTicket(ctx, ticket, token, ttl); err != nil {
+		sts.logger.Error("failed to save stream ticket",
+			zap.Error(err),
+		)
+		return nil, dto.ErrCreateStreamTicket
+	}
+
+	return &dto.StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// RedeemTicket return token dan user id pemilik ticket. token dicek ulang supaya stream tetap ditutup saat
+// token habis, sama seperti koneksi websocket
+func (sts *streamTicketService) RedeemTicket(ctx context.Context, ticket string) (string, string, error) {
+	token, found, err := sts.streamTicketRepo.ConsumeStreamTicket(ctx, ticket)
+	if err != nil {
+		sts.logger.Error("failed to consume stream ticket",
+			zap.Error(err),
+		)
+		return "", "", dto.ErrCreateStreamTicket
+	}
+	if !found {
+		return "", "", dto.ErrStreamTicketInvalid
+	}
+
+	if _, err := sts.jwt.GetExpiryByToken(token); err != nil {
+		return "", "", dto.ErrStreamTicketInvalid
+	}
+	userID, err := sts.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return "", "", dto.ErrStreamTicketInvalid
+	}
+
+	return token, userID, nil
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Amierza/chat-service/constants"
+	"github.com/Amierza/chat-service/dto"
+	"github.com/Amierza/chat-service/entity"
+	"github.com/Amierza/chat-service/repository"
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	summaryConsumerPrefetch     = 10
+	summaryConsumerRetryBackoff = 5 * time.Second
+)
+
+type (
+	ISummaryConsumerService interface {
+		Run(ctx context.Context)
+		HandleResult(ctx context.Context, body []byte) error
+	}
+
+	summaryConsumerService struct {
+		sessionRepo      repository.ISessionRepository
+		notificationRepo repository.INotificationRepository
+		userRepo         repository.IUserRepository
+		logger           *zap.Logger
+		rabbitmq         *amqp091.Connection
+		wsService        IWebsocketService
+		messagePersister IMessagePersisterService
+	}
+)
+
+func NewSummaryConsumerService(sessionRepo repository.ISessionRepository, notificationRepo repository.INotificationRepository, userRepo repository.IUserRepository, logger *zap.Logger, rabbitmq *amqp091.Connection, wsService IWebsocketService, messagePersister IMessagePersisterService) *summaryConsumerService {
+	return &summaryConsumerService{
+		sessionRepo:      sessionRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		logger:           logger,
+		rabbitmq:         rabbitmq,
+		wsService:        wsService,
+		messagePersister: messagePersister,
+	}
+}
+
+// Run mendengarkan queue summary_result dan membuka ulang channel kalau terputus
+func (scs *summaryConsumerService) Run(ctx context.Context) {
+	for {
+		if err := scs.consume(ctx); err != nil {
+			scs.logger.Error("summary result consumer stopped",
+				zap.Error(err),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			scs.logger.Info("summary result consumer shut down")
+			return
+		case <-time.After(summaryConsumerRetryBackoff):
+		}
+	}
+}
+
+func (scs *summaryConsumerService) consume(ctx context.Context) error {
+	ch, err := scs.rabbitmq.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	queueName := constants.ENUM_QUEUE_SUMMARY_RESULT
+	if _, err := ch.QueueDeclare(
+		queueName,
+		true,  // durable
+		false, // autoDelete
+		false, // exclusive
+		false, // noWait
+		nil,   // args
+	); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	if err := ch.Qos(summaryConsumerPrefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set qos: %w", err)
+	}
+
+	deliveries, err := ch.Consume(
+		queueName,
+		"",    // consumer
+		false, // autoAck
+		false, // exclusive
+		false, // noLocal
+		false, // noWait
+		nil,   // args
+	)
+	if err != nil {
+		return fmt.Errorf("failed to consume queue: %w", err)
+	}
+
+	scs.logger.Info("summary result consumer started",
+		zap.String("queue", queueName),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+
+			err := scs.HandleResult(ctx, d.Body)
+			switch {
+			case err == nil:
+				d.Ack(false)
+			case errors.Is(err, dto.ErrInvalidSummaryResult):
+				// payload rusak tidak akan pernah berhasil, jangan di-requeue
+				scs.logger.Warn("drop invalid summary result",
+					zap.ByteString("body", d.Body),
+					zap.Error(err),
+				)
+				d.Nack(false, false)
+			default:
+				scs.logger.Error("failed to handle summary result, requeue",
+					zap.Error(err),
+				)
+				d.Nack(false, true)
+			}
+		}
+	}
+}
+
+func (scs *summaryConsumerService) HandleResult(ctx context.Context, body []byte) error {
+	var result dto.TaskSummaryResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("%w: %v", dto.ErrInvalidSummaryResult, err)
+	}
+	if result.SessionID == uuid.Nil || strings.TrimSpace(result.Summary) == "" {
+		return fmt.Errorf("%w: session_id and summary are required", dto.ErrInvalidSummaryResult)
+	}
+
+	sessionID := result.SessionID.String()
+	session, found, err := scs.sessionRepo.GetActiveSessionBySessionID(ctx, nil, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed get session: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: session %s not found", dto.ErrInvalidSummaryResult, sessionID)
+	}
+
+	// result yang terkirim ulang untuk session yang sudah selesai cukup di-ack
+	if session.Status == constants.ENUM_SESSION_STATUS_FINSIHED {
+		scs.logger.Info("summary result already applied",
+			zap.String("session_id", sessionID),
+		)
+		return nil
+	}
+	if session.Status != constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY {
+		return fmt.Errorf("%w: session %s is %s", dto.ErrInvalidSummaryResult, sessionID, session.Status)
+	}
+
+	// pastikan history chat lengkap di postgres sebelum session ditutup
+	if _, err := scs.messagePersister.Flush(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed flush session messages: %w", err)
+	}
+
+	note := &entity.Note{
+		ID:        uuid.New(),
+		Content:   result.Summary,
+		SessionID: session.ID,
+	}
+	var receiverUserIDs []string
+	err = scs.sessionRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		// status dipindah lebih dulu, result ganda yang kalah balapan tidak membuat note / notif lagi
+		updated, err := scs.sessionRepo.UpdateSessionStatus(ctx, tx, sessionID, constants.ENUM_SESSION_STATUS_PROCESSING_SUMMARY, constants.ENUM_SESSION_STATUS_FINSIHED)
+		if err != nil {
+			return dto.ErrUpdateSession
+		}
+		if !updated {
+			return dto.ErrSummaryAlreadyApplied
+		}
+
+		existing, found, err := scs.sessionRepo.GetNoteSummaryBySessionID(ctx, tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if found {
+			note = existing
+		} else if err := scs.sessionRepo.CreateNote(ctx, tx, note); err != nil {
+			return dto.ErrCreateNote
+		}
+
+		// notifikasi ikut commit bersama status, kalau tidak result ulangan dianggap sudah diterapkan
+		// dan notifikasi yang hilang tidak pernah dibuat lagi
+		receiverUserIDs, err = scs.createSummaryNotifications(ctx, tx, session)
+		return err
+	})
+	if errors.Is(err, dto.ErrSummaryAlreadyApplied) {
+		scs.logger.Info("summary result already applied",
+			zap.String("session_id", sessionID),
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	scs.logger.Info("session finished with summary note",
+		zap.String("session_id", sessionID),
+		zap.String("note_id", note.ID.String()),
+	)
+
+	scs.notifySummaryReady(session, note, receiverUserIDs)
+
+	return nil
+}
+
+// createSummaryNotifications return user id penerima untuk push websocket setelah commit
+func (scs *summaryConsumerService) createSummaryNotifications(ctx context.Context, tx *gorm.DB, session *entity.Session) ([]string, error) {
+	var receiverIDs []uuid.UUID
+	if session.Thesis.StudentID != uuid.Nil {
+		receiverIDs = append(receiverIDs, session.Thesis.StudentID)
+	}
+	for _, sup := range session.Thesis.Supervisors {
+		receiverIDs = append(receiverIDs, sup.LecturerID)
+	}
+
+	// resolve receiver entity IDs (student/lecturer) -> user.id
+	userIDs := make([]string, 0, len(receiverIDs))
+	for _, rid := range receiverIDs {
+		receiverUser, found, err := scs.userRepo.GetUserByStudentOrLecturerID(ctx, tx, rid.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed resolve receiver user: %w", err)
+		}
+		if !found {
+			scs.logger.Warn("failed to resolve receiver user",
+				zap.String("receiver_entity_id", rid.String()),
+			)
+			continue
+		}
+
+		notif := &entity.Notification{
+			ID:      uuid.New(),
+			Title:   "Session Summary Ready",
+			Message: fmt.Sprintf("Summary for thesis session \"%s\" is ready.", session.Thesis.Title),
+			IsRead:  false,
+			UserID:  receiverUser.ID,
+		}
+		if err := scs.notificationRepo.CreateNotification(ctx, tx, notif); err != nil {
+			scs.logger.Error("failed to create summary notification",
+				zap.String("session_id", session.ID.String()),
+				zap.String("receiver_user_id", receiverUser.ID.String()),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+		userIDs = append(userIDs, receiverUser.ID.String())
+	}
+
+	return userIDs, nil
+}
+
+func (scs *summaryConsumerService) notifySummaryReady(session *entity.Session, note *entity.Note, receiverUserIDs []string) {
+	data, _ := json.Marshal(dto.SummaryEventPublish{
+		Event:     "summary_ready",
+		SessionID: session.ID,
+		ThesisID:  session.ThesisID,
+		NoteID:    note.ID,
+	})
+
+	for _, userID := range receiverUserIDs {
+		if err := scs.wsService.SendToUser(userID, data); err != nil {
+			scs.logger.Info("summary_ready receiver is offline",
+				zap.String("receiver_user_id", userID),
+			)
+		}
+	}
+}
@@ -21,23 +21,41 @@ type (
 	}
 
 	thesisService struct {
-		thesisRepo repository.IThesisRepository
-		userRepo   repository.IUserRepository
-		logger     *zap.Logger
-		jwt        jwt.IJWT
+		thesisRepo         repository.IThesisRepository
+		userRepo           repository.IUserRepository
+		logger             *zap.Logger
+		jwt                jwt.IJWT
+		participantService IParticipantService
 	}
 )
 
-func NewThesisService(thesisRepo repository.IThesisRepository, userRepo repository.IUserRepository, logger *zap.Logger, jwt jwt.IJWT) *thesisService {
+func NewThesisService(thesisRepo repository.IThesisRepository, userRepo repository.IUserRepository, logger *zap.Logger, jwt jwt.IJWT, participantService IParticipantService) *thesisService {
 	return &thesisService{
-		thesisRepo: thesisRepo,
-		userRepo:   userRepo,
-		logger:     logger,
-		jwt:        jwt,
+		thesisRepo:         thesisRepo,
+		userRepo:           userRepo,
+		logger:             logger,
+		jwt:                jwt,
+		participantService: participantService,
 	}
 }
 
 func (us *thesisService) GetDetail(ctx context.Context, id string) (*dto.ThesisResponse, error) {
+	token := ctx.Value("Authorization").(string)
+	userIDString, err := us.jwt.GetUserIDByToken(token)
+	if err != nil {
+		return nil, dto.ErrGetUserIDFromToken
+	}
+	user, found, err := us.userRepo.GetUserByID(ctx, nil, userIDString)
+	if err != nil {
+		us.logger.Error("failed to get user from user id string",
+			zap.Error(err),
+		)
+		return nil, dto.ErrGetUserByID
+	}
+	if !found {
+		return nil, dto.ErrNotFound
+	}
+
 	data, found, err := us.thesisRepo.GetThesisByID(ctx, nil, id)
 	if err != nil {
 		us.logger.Error("failed to get thesis by id",
@@ -52,6 +70,9 @@ func (us *thesisService) GetDetail(ctx context.Context, id string) (*dto.ThesisR
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := us.participantService.AuthorizeThesis(ctx, data, user); err != nil {
+		return nil, err
+	}
 
 	thesis := &dto.ThesisResponse{
 		ID:          data.ID,
@@ -109,7 +130,7 @@ func (ts *thesisService) Update(ctx context.Context, req *dto.UpdateThesisReques
 			zap.String("user_id", userIDString),
 			zap.String("role", string(user.Role)),
 		)
-		return nil, fmt.Errorf("lecturer cannot update thesis")
+		return nil, dto.ErrLecturerCannotUpdateThesis
 	}
 
 	existingThesis, found, err := ts.thesisRepo.GetThesisByID(ctx, nil, req.ID)
@@ -126,6 +147,9 @@ func (ts *thesisService) Update(ctx context.Context, req *dto.UpdateThesisReques
 		)
 		return nil, dto.ErrNotFound
 	}
+	if err := ts.participantService.AuthorizeThesis(ctx, existingThesis, user); err != nil {
+		return nil, err
+	}
 
 	if !entity.IsValidProgress(req.Progress) {
 		ts.logger.Warn("invalid thesis progress",
@@ -203,7 +227,15 @@ func (ts *thesisService) GetAllByLecturer(ctx context.Context, req response.Pagi
 			zap.String("user_id", userIDString),
 			zap.String("role", string(user.Role)),
 		)
-		return dto.ThesisPaginationResponse{}, fmt.Errorf("student cannot read all lecturer thesis")
+		return dto.ThesisPaginationResponse{}, dto.ErrStudentCannotReadLecturerTheses
+	}
+	// dosen hanya boleh melihat daftar thesis bimbingannya sendiri
+	if user.LecturerID == nil || user.LecturerID.String() != lecturerID {
+		ts.logger.Warn("lecturer tried to read other lecturer thesis",
+			zap.String("user_id", userIDString),
+			zap.String("lecturer_id", lecturerID),
+		)
+		return dto.ThesisPaginationResponse{}, dto.ErrNotThesisParticipant
 	}
 
 	datas, err := ts.thesisRepo.GetAllThesesByLecturerIDWithPagination(ctx, nil, req, lecturerID)
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Amierza/chat-service/dto"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsMaxMessageSize = 64 * 1024
+	wsSendQueueSize  = 256
+
+	// batas waktu client mengirim frame auth kalau token tidak dikirim lewat Sec-WebSocket-Protocol
+	wsAuthWait = 10 * time.Second
+
+	// close code 4000-4999 bebas dipakai aplikasi
+	wsCloseUnauthorized = 4001
+	wsCloseTokenExpired = 4002 // client harus ambil token baru lalu connect / auth ulang
+	wsCloseSlowConsumer = 4008
+)
+
+type (
+	// wsClient satu koneksi (satu device). semua write lewat queue send dan hanya dikerjakan writePump,
+	// karena gorilla/websocket tidak mengizinkan concurrent writer
+	wsClient struct {
+		id          string
+		userID      string
+		token       string
+		expiry      *time.Timer
+		conn        *websocket.Conn
+		send        chan []byte
+		done        chan struct{}
+		closeOnce   sync.Once
+		closeCode   int
+		closeReason string
+		connectedAt time.Time
+		metrics     *wsMetrics
+		rooms       map[string]struct{}  // dijaga oleh mutex webSocketService
+		lastFrame   map[string]time.Time // hanya diakses goroutine pembaca koneksi
+	}
+
+	wsMetrics struct {
+		activeConnections       atomic.Int64
+		connectionsOpened       atomic.Int64
+		connectionsClosed       atomic.Int64
+		messagesSent            atomic.Int64
+		messagesDropped         atomic.Int64
+		slowConsumerDisconnects atomic.Int64
+		pongTimeouts            atomic.Int64
+		writeErrors             atomic.Int64
+		framesThrottled         atomic.Int64
+	}
+)
+
+func newWSClient(userID, token string, conn *websocket.Conn, metrics *wsMetrics) *wsClient {
+	metrics.connectionsOpened.Add(1)
+	metrics.activeConnections.Add(1)
+
+	return &wsClient{
+		id:          uuid.NewString(),
+		userID:      userID,
+		token:       token,
+		conn:        conn,
+		send:        make(chan []byte, wsSendQueueSize),
+		done:        make(chan struct{}),
+		connectedAt: time.Now(),
+		metrics:     metrics,
+		rooms:       make(map[string]struct{}),
+		lastFrame:   make(map[string]time.Time),
+	}
+}
+
+// enqueue tidak pernah blocking. queue penuh berarti client terlalu lambat, koneksinya diputus
+// supaya tidak menahan pengiriman ke user lain
+func (c *wsClient) enqueue(message []byte) bool {
+	select {
+	case <-c.done:
+		c.metrics.messagesDropped.Add(1)
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		c.metrics.messagesDropped.Add(1)
+		c.metrics.slowConsumerDisconnects.Add(1)
+		log.Printf("[WS] slow consumer %s, queue full (%d), disconnecting", c.userID, wsSendQueueSize)
+		c.close(wsCloseSlowConsumer, "slow consumer")
+		return false
+	}
+}
+
+// close aman dipanggil berkali-kali, close frame dikirim oleh writePump
+func (c *wsClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// writePump satu-satunya goroutine yang menulis ke koneksi
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.metrics.writeErrors.Add(1)
+				log.Printf("[WS] write error to %s: %v", c.userID, err)
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			c.metrics.messagesSent.Add(1)
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.metrics.writeErrors.Add(1)
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				c.conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+					time.Now().Add(wsWriteWait),
+				)
+			}
+			return
+		}
+	}
+}
+
+// prepareRead pasang batas ukuran frame dan read deadline yang diperpanjang setiap pong,
+// onPong dipakai sebagai heartbeat (contoh: refresh presence)
+func (c *wsClient) prepareRead(onPong func()) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		onPong()
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+}
+
+// allow return false kalau frame type yang sama sudah diterima kurang dari interval yang lalu
+func (c *wsClient) allow(frameType string, interval time.Duration) bool {
+	if interval <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if last, ok := c.lastFrame[frameType]; ok && now.Sub(last) < interval {
+		return false
+	}
+	c.lastFrame[frameType] = now
+
+	return true
+}
+
+// expireAt menutup koneksi dengan wsCloseTokenExpired saat token habis, dipanggil ulang setiap re-auth
+func (c *wsClient) expireAt(at time.Time) {
+	d := time.Until(at)
+	if c.expiry == nil {
+		c.expiry = time.AfterFunc(d, func() {
+			log.Printf("[WS] token of %s expired, closing connection", c.userID)
+			c.close(wsCloseTokenExpired, "token expired")
+		})
+		return
+	}
+	c.expiry.Reset(d)
+}
+
+func (c *wsClient) closed() {
+	if c.expiry != nil {
+		c.expiry.Stop()
+	}
+	c.metrics.activeConnections.Add(-1)
+	c.metrics.connectionsClosed.Add(1)
+
+	log.Printf("[WS] user %s disconnected after %s (code %d)", c.userID, time.Since(c.connectedAt).Round(time.Second), c.closeCode)
+}
+
+func (m *wsMetrics) snapshot(activeUsers int) dto.WSMetricsResponse {
+	return dto.WSMetricsResponse{
+		ActiveConnections:       m.activeConnections.Load(),
+		ActiveUsers:             int64(activeUsers),
+		ConnectionsOpened:       m.connectionsOpened.Load(),
+		ConnectionsClosed:       m.connectionsClosed.Load(),
+		MessagesSent:            m.messagesSent.Load(),
+		MessagesDropped:         m.messagesDropped.Load(),
+		SlowConsumerDisconnects: m.slowConsumerDisconnects.Load(),
+		PongTimeouts:            m.pongTimeouts.Load(),
+		WriteErrors:             m.writeErrors.Load(),
+		FramesThrottled:         m.framesThrottled.Load(),
+	}
+}
@@ -1,16 +1,27 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Amierza/chat-service/dto"
-	"github.com/Amierza/chat-service/helper"
 	"github.com/Amierza/chat-service/jwt"
+	"github.com/Amierza/chat-service/repository"
 	"github.com/Amierza/chat-service/response"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
 )
@@ -18,108 +29,816 @@ import (
 type (
 	IWebsocketService interface {
 		HandleWebSocket(ctx *gin.Context)
+		HandleEventStream(ctx *gin.Context, rooms []string)
 		SendToUser(userID string, message []byte) error
+		SendEphemeralToUser(userID string, message []byte) error
+		BroadcastToRoom(room string, userIDs []string, message []byte) error
+		Replay(ctx context.Context, lastEventID int64) (*dto.WSResumeResponse, error)
+		SubscribeRoom(ctx context.Context, room string) error
+		UnsubscribeRoom(ctx context.Context, room string) error
+		RegisterHandler(frameType string, handler WSHandlerFunc)
+		Throttle(frameType string, interval time.Duration)
+		RegisterConnectionListener(listener WSConnectionListener)
+		Run(ctx context.Context)
+		Metrics() dto.WSMetricsResponse
+	}
+
+	// WSHandlerFunc memproses payload frame dari client, ctx sudah berisi "Authorization" dan "ConnectionID".
+	// hasil yang dikembalikan dikirim balik sebagai payload ack
+	WSHandlerFunc func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+
+	// WSConnectionListener dipanggil di setiap lifecycle koneksi, contoh: presence
+	WSConnectionListener interface {
+		Connected(userID, connID string)
+		Heartbeat(userID, connID string)
+		Disconnected(userID, connID string)
 	}
 
 	webSocketService struct {
-		upgrader    websocket.Upgrader
-		jwt         jwt.IJWT
-		redis       *redis.Client
-		connections map[string]*websocket.Conn
-		mu          sync.RWMutex
+		upgrader     websocket.Upgrader
+		jwt          jwt.IJWT
+		redis        *redis.Client
+		eventLogRepo repository.IEventLogRepository
+		connections  map[string]map[*wsClient]struct{} // satu user bisa punya banyak device
+		clients      map[string]*wsClient              // index koneksi berdasarkan id
+		rooms        map[string]map[*wsClient]struct{} // contoh room: session:<id>
+		handlers     map[string]WSHandlerFunc
+		throttles    map[string]time.Duration
+		listeners    []WSConnectionListener
+		metrics      *wsMetrics
+		mu           sync.RWMutex
+
+		// origin browser yang boleh upgrade, dari env WS_ALLOWED_ORIGINS
+		allowedOrigins []string
+
+		// fan-out antar instance lewat redis pub/sub, tiap instance subscribe channel user / room
+		// yang punya koneksi di instance tersebut
+		instanceID string
+		pubsub     *redis.PubSub
+		subscribed map[string]bool
+		subMu      sync.Mutex
 	}
 )
 
-func NewWebSocketService(jwt jwt.IJWT, redis *redis.Client) *webSocketService {
-	return &webSocketService{
+func NewWebSocketService(jwt jwt.IJWT, redis *redis.Client, eventLogRepo repository.IEventLogRepository) *webSocketService {
+	wh := &webSocketService{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
+			Subprotocols: []string{"bearer"},
 		},
-		jwt:         jwt,
-		redis:       redis,
-		connections: make(map[string]*websocket.Conn),
+		jwt:          jwt,
+		redis:        redis,
+		eventLogRepo: eventLogRepo,
+		connections:  make(map[string]map[*wsClient]struct{}),
+		clients:      make(map[string]*wsClient),
+		rooms:        make(map[string]map[*wsClient]struct{}),
+		handlers:     make(map[string]WSHandlerFunc),
+		throttles:    make(map[string]time.Duration),
+		metrics:      &wsMetrics{},
+		instanceID:   uuid.NewString(),
+		pubsub:       redis.Subscribe(context.Background()),
+		subscribed:   make(map[string]bool),
+
+		allowedOrigins: parseAllowedOrigins(os.Getenv("WS_ALLOWED_ORIGINS")),
 	}
+	wh.upgrader.CheckOrigin = wh.checkOrigin
+
+	return wh
+}
+
+func userChannel(userID string) string {
+	return fmt.Sprintf("ws:user:%s", userID)
+}
+func roomChannel(room string) string {
+	return fmt.Sprintf("ws:room:%s", room)
+}
+
+// RegisterConnectionListener mendaftarkan listener lifecycle koneksi
+func (wh *webSocketService) RegisterConnectionListener(listener WSConnectionListener) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.listeners = append(wh.listeners, listener)
+}
+
+// RegisterHandler mendaftarkan handler untuk frame dengan type tertentu
+func (wh *webSocketService) RegisterHandler(frameType string, handler WSHandlerFunc) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.handlers[frameType] = handler
+}
+
+// Throttle membatasi frame type tertentu maksimal sekali per interval untuk setiap koneksi
+func (wh *webSocketService) Throttle(frameType string, interval time.Duration) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.throttles[frameType] = interval
 }
 
+// HandleWebSocket token tidak lagi lewat query string (tercatat di access log proxy), tapi lewat
+// Sec-WebSocket-Protocol: "bearer, <token>" atau frame pertama {type: "auth", payload: {token}}
 func (wh *webSocketService) HandleWebSocket(ctx *gin.Context) {
-	tokenString := ctx.Query("token")
-	if tokenString == "" {
-		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_TOKEN_NOT_FOUND, "missing token", nil)
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
-		return
+	tokenString := tokenFromSubprotocol(ctx.Request)
+	if tokenString != "" {
+		if _, err := wh.jwt.ValidateToken(tokenString); err != nil {
+			res := response.BuildResponseFailed(dto.MESSAGE_FAILED_TOKEN_NOT_VALID, "invalid token", nil)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, res)
+			return
+		}
 	}
 
-	// validasi token
-	_, err := wh.jwt.ValidateToken(tokenString)
+	conn, err := wh.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 	if err != nil {
-		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_TOKEN_NOT_VALID, "invalid token", nil)
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		log.Printf("Failed to upgrade connection: %v", err)
 		return
 	}
 
+	if tokenString == "" {
+		tokenString, err = wh.awaitAuthFrame(conn)
+		if err != nil {
+			log.Printf("[WS] authentication failed: %v", err)
+			conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(wsCloseUnauthorized, "unauthorized"),
+				time.Now().Add(wsWriteWait),
+			)
+			conn.Close()
+			return
+		}
+	}
+
 	userID, err := wh.jwt.GetUserIDByToken(tokenString)
 	if err != nil {
-		res := response.BuildResponseFailed(dto.MESSAGE_FAILED_TOKEN_NOT_VALID, "invalid token", nil)
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		conn.Close()
 		return
 	}
-
-	conn, err := wh.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	expiresAt, err := wh.jwt.GetExpiryByToken(tokenString)
 	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
+		conn.Close()
 		return
 	}
-	defer func() {
-		wh.mu.Lock()
-		delete(wh.connections, userID)
-		wh.mu.Unlock()
 
-		helper.SetOffline(userID)
-		conn.Close()
-	}()
+	client := newWSClient(userID, tokenString, conn, wh.metrics)
+	client.expireAt(expiresAt)
+	defer wh.unregister(client, websocket.CloseNormalClosure)
+	go client.writePump()
 
-	wh.mu.Lock()
-	wh.connections[userID] = conn
-	wh.mu.Unlock()
+	devices := wh.register(client, nil)
+	log.Printf("User %v connected via WebSocket (%d device)", userID, devices)
 
-	helper.SetOnline(userID)
-	log.Printf("User %v connected via WebSocket", userID)
+	// client yang reconnect bisa langsung minta event yang terlewat
+	if lastEventID, err := strconv.ParseInt(ctx.Query("last_event_id"), 10, 64); err == nil {
+		wh.replayTo(client, lastEventID)
+	}
 
+	client.prepareRead(func() {
+		wh.notify(func(l WSConnectionListener) { l.Heartbeat(userID, client.id) })
+	})
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
+			// read deadline habis berarti pong tidak pernah datang (koneksi half-open)
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				wh.metrics.pongTimeouts.Add(1)
+			}
 			log.Printf("Error reading message: %v", err)
 			break
 		}
-		log.Printf("Received message from %s: %s", userID, msg)
+		wh.dispatch(ctx, client, msg)
 	}
 }
 
-// SendToUser mengirim message langsung ke user tertentu
-func (wh *webSocketService) SendToUser(userID string, message []byte) error {
+// tokenFromSubprotocol browser tidak bisa set header Authorization saat upgrade,
+// jadi token dikirim sebagai subprotocol kedua setelah "bearer"
+func tokenFromSubprotocol(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == "bearer" {
+			return protocols[i+1]
+		}
+	}
+
+	return ""
+}
+
+// awaitAuthFrame menunggu frame auth pertama, dipanggil sebelum writePump jalan jadi boleh write langsung
+func (wh *webSocketService) awaitAuthFrame(conn *websocket.Conn) (string, error) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsAuthWait))
+
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		return "", err
+	}
+
+	var frame dto.WSEnvelope
+	if err := json.Unmarshal(msg, &frame); err != nil || frame.Type != "auth" {
+		return "", dto.ErrInvalidWSPayload
+	}
+	var req dto.WSAuthPayload
+	if err := json.Unmarshal(frame.Payload, &req); err != nil || req.Token == "" {
+		return "", dto.ErrInvalidWSPayload
+	}
+	if _, err := wh.jwt.ValidateToken(req.Token); err != nil {
+		return "", err
+	}
+
+	ack, _ := json.Marshal(dto.WSEnvelope{Type: "ack", ID: frame.ID})
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := conn.WriteMessage(websocket.TextMessage, ack); err != nil {
+		return "", err
+	}
+
+	return req.Token, nil
+}
+
+// reauth mengganti token koneksi yang masih terbuka (contoh: setelah refresh token) tanpa reconnect
+func (wh *webSocketService) reauth(client *wsClient, payload json.RawMessage) error {
+	var req dto.WSAuthPayload
+	if err := json.Unmarshal(payload, &req); err != nil || req.Token == "" {
+		return dto.ErrInvalidWSPayload
+	}
+
+	userID, err := wh.jwt.GetUserIDByToken(req.Token)
+	if err != nil {
+		return err
+	}
+	if userID != client.userID {
+		return dto.ErrWSAuthUserMismatch
+	}
+	expiresAt, err := wh.jwt.GetExpiryByToken(req.Token)
+	if err != nil {
+		return err
+	}
+
+	client.token = req.Token
+	client.expireAt(expiresAt)
+
+	return nil
+}
+
+// checkOrigin origin kosong (client native / mobile) selalu boleh, selain itu harus ada di
+// WS_ALLOWED_ORIGINS. kalau env kosong hanya origin dengan host yang sama yang boleh
+func (wh *webSocketService) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if len(wh.allowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+
+	for _, allowed := range wh.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	log.Printf("[WS] rejected origin %s", origin)
+
+	return false
+}
+
+func parseAllowedOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins
+}
+
+// dispatch menjalankan handler sesuai type frame lalu membalas ack / error dengan id yang sama
+func (wh *webSocketService) dispatch(ctx *gin.Context, client *wsClient, msg []byte) {
+	userID := client.userID
+
+	var frame dto.WSEnvelope
+	if err := json.Unmarshal(msg, &frame); err != nil {
+		wh.reply(client, userID, dto.WSEnvelope{Type: "error"}, "invalid_frame", err)
+		return
+	}
+
+	// auth ulang ditangani langsung karena mengubah state koneksi
+	if frame.Type == "auth" {
+		if err := wh.reauth(client, frame.Payload); err != nil {
+			wh.reply(client, userID, frame, "unauthorized", err)
+			return
+		}
+		wh.send(client, userID, dto.WSEnvelope{Type: "ack", ID: frame.ID})
+		return
+	}
+
 	wh.mu.RLock()
-	conn, ok := wh.connections[userID]
+	handler, ok := wh.handlers[frame.Type]
+	interval := wh.throttles[frame.Type]
 	wh.mu.RUnlock()
+	if !ok {
+		wh.reply(client, userID, frame, "unknown_type", fmt.Errorf("unknown frame type %q", frame.Type))
+		return
+	}
+
+	// frame yang kena throttle dibuang, error hanya dikirim kalau client menunggu ack supaya client
+	// yang spam tanpa ack tidak dibanjiri error
+	if !client.allow(frame.Type, interval) {
+		wh.metrics.framesThrottled.Add(1)
+		if frame.Ack {
+			wh.reply(client, userID, frame, "throttled", dto.ErrWSFrameThrottled)
+		}
+		return
+	}
+
+	// service membaca token dari ctx sama seperti request REST
+	reqCtx := context.WithValue(ctx.Request.Context(), "Authorization", client.token)
+	reqCtx = context.WithValue(reqCtx, "ConnectionID", client.id)
+	result, err := handler(reqCtx, frame.Payload)
+	if err != nil {
+		log.Printf("Failed to handle frame %q from %s: %v", frame.Type, userID, err)
+		code := "command_failed"
+		if errors.Is(err, dto.ErrRateLimited) {
+			code = "rate_limited"
+		}
+		wh.reply(client, userID, frame, code, err)
+		return
+	}
+
+	if frame.Ack {
+		payload, _ := json.Marshal(result)
+		wh.send(client, userID, dto.WSEnvelope{
+			Type:    "ack",
+			ID:      frame.ID,
+			Payload: payload,
+		})
+	}
+}
+
+func (wh *webSocketService) reply(client *wsClient, userID string, frame dto.WSEnvelope, errType string, err error) {
+	payload, _ := json.Marshal(dto.WSErrorPayload{
+		Type:    errType,
+		Message: err.Error(),
+	})
+	wh.send(client, userID, dto.WSEnvelope{
+		Type:    "error",
+		ID:      frame.ID,
+		Payload: payload,
+	})
+}
+
+func (wh *webSocketService) send(client *wsClient, userID string, envelope dto.WSEnvelope) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("Failed to marshal frame for %s: %v", userID, err)
+		return
+	}
 
+	if !client.enqueue(data) {
+		log.Printf("Error sending frame to %s: connection closed", userID)
+	}
+}
+
+// register mendaftarkan koneksi (websocket / sse) beserta room awalnya, lalu subscribe channel redis
+// yang dibutuhkan dan memberi tahu listener. return jumlah device user saat ini
+func (wh *webSocketService) register(client *wsClient, rooms []string) int {
+	devices := wh.addClient(client)
+
+	wh.mu.Lock()
+	for _, room := range rooms {
+		wh.joinRoom(client, room)
+	}
+	wh.mu.Unlock()
+
+	wh.syncChannel(userChannel(client.userID), true)
+	for _, room := range rooms {
+		wh.syncChannel(roomChannel(room), true)
+	}
+	wh.notify(func(l WSConnectionListener) { l.Connected(client.userID, client.id) })
+
+	return devices
+}
+
+// unregister kebalikan register, dipanggil sekali saat koneksi selesai
+func (wh *webSocketService) unregister(client *wsClient, closeCode int) {
+	rooms := wh.removeClient(client)
+	wh.syncChannel(userChannel(client.userID), wh.hasUser(client.userID))
+	for _, room := range rooms {
+		wh.syncChannel(roomChannel(room), wh.hasRoom(room))
+	}
+	client.close(closeCode, "")
+	client.closed()
+	wh.notify(func(l WSConnectionListener) { l.Disconnected(client.userID, client.id) })
+}
+
+// addClient mendaftarkan koneksi baru dan mengembalikan jumlah device user saat ini
+func (wh *webSocketService) addClient(client *wsClient) int {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	clients, ok := wh.connections[client.userID]
 	if !ok {
-		// user tidak online → biar Start() bisa fallback bikin notif
-		return fmt.Errorf("user %s not connected", userID)
+		clients = make(map[*wsClient]struct{})
+		wh.connections[client.userID] = clients
+	}
+	clients[client] = struct{}{}
+	wh.clients[client.id] = client
+
+	return len(clients)
+}
+
+// removeClient menghapus satu koneksi beserta semua room-nya, map user ikut dihapus kalau device
+// terakhirnya sudah disconnect. return room yang tadi diikuti koneksi ini
+func (wh *webSocketService) removeClient(client *wsClient) []string {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	delete(wh.clients, client.id)
+
+	rooms := make([]string, 0, len(client.rooms))
+	for room := range client.rooms {
+		rooms = append(rooms, room)
+		wh.leaveRoom(client, room)
+	}
+
+	clients, ok := wh.connections[client.userID]
+	if !ok {
+		return rooms
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(wh.connections, client.userID)
+	}
+
+	return rooms
+}
+
+// joinRoom harus dipanggil dengan wh.mu terkunci
+func (wh *webSocketService) joinRoom(client *wsClient, room string) {
+	members, ok := wh.rooms[room]
+	if !ok {
+		members = make(map[*wsClient]struct{})
+		wh.rooms[room] = members
+	}
+	members[client] = struct{}{}
+	client.rooms[room] = struct{}{}
+}
+
+// leaveRoom harus dipanggil dengan wh.mu terkunci
+func (wh *webSocketService) leaveRoom(client *wsClient, room string) {
+	delete(client.rooms, room)
+
+	members, ok := wh.rooms[room]
+	if !ok {
+		return
+	}
+	delete(members, client)
+	if len(members) == 0 {
+		delete(wh.rooms, room)
+	}
+}
+
+func (wh *webSocketService) hasUser(userID string) bool {
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+
+	return len(wh.connections[userID]) > 0
+}
+
+func (wh *webSocketService) hasRoom(room string) bool {
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+
+	return len(wh.rooms[room]) > 0
+}
+
+// clientFromContext mengambil koneksi pemanggil dari ctx command websocket
+func (wh *webSocketService) clientFromContext(ctx context.Context) (*wsClient, error) {
+	connID, _ := ctx.Value("ConnectionID").(string)
+
+	wh.mu.RLock()
+	client, ok := wh.clients[connID]
+	wh.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("websocket connection %q not found", connID)
 	}
 
-	err := conn.WriteMessage(websocket.TextMessage, message)
+	return client, nil
+}
+
+// SubscribeRoom memasukkan koneksi pemanggil ke room, otorisasi dilakukan oleh pemanggil
+func (wh *webSocketService) SubscribeRoom(ctx context.Context, room string) error {
+	client, err := wh.clientFromContext(ctx)
 	if err != nil {
-		log.Printf("Error sending message to %s: %v", userID, err)
+		return err
+	}
 
-		// kalau koneksi rusak → remove biar gak nyangkut
-		wh.mu.Lock()
-		delete(wh.connections, userID)
+	wh.mu.Lock()
+	// koneksi bisa saja sudah disconnect selama command diproses
+	if _, ok := wh.clients[client.id]; !ok {
 		wh.mu.Unlock()
+		return fmt.Errorf("websocket connection %q already closed", client.id)
+	}
+	wh.joinRoom(client, room)
+	wh.mu.Unlock()
+
+	wh.syncChannel(roomChannel(room), true)
+	log.Printf("[WS] user %s subscribed to %s", client.userID, room)
 
+	return nil
+}
+
+func (wh *webSocketService) UnsubscribeRoom(ctx context.Context, room string) error {
+	client, err := wh.clientFromContext(ctx)
+	if err != nil {
 		return err
 	}
 
-	log.Printf("[WS SEND] to userID: '%s'", userID)
+	wh.mu.Lock()
+	wh.leaveRoom(client, room)
+	wh.mu.Unlock()
+
+	wh.syncChannel(roomChannel(room), wh.hasRoom(room))
+	log.Printf("[WS] user %s unsubscribed from %s", client.userID, room)
+
+	return nil
+}
+
+func (wh *webSocketService) notify(fn func(l WSConnectionListener)) {
+	wh.mu.RLock()
+	listeners := make([]WSConnectionListener, len(wh.listeners))
+	copy(listeners, wh.listeners)
+	wh.mu.RUnlock()
+
+	for _, l := range listeners {
+		fn(l)
+	}
+}
+
+// Metrics ringkasan lifecycle koneksi websocket di instance ini
+func (wh *webSocketService) Metrics() dto.WSMetricsResponse {
+	wh.mu.RLock()
+	activeUsers := len(wh.connections)
+	wh.mu.RUnlock()
+
+	return wh.metrics.snapshot(activeUsers)
+}
+
+// Run meneruskan message dari instance lain ke koneksi lokal sampai ctx selesai
+func (wh *webSocketService) Run(ctx context.Context) {
+	defer wh.pubsub.Close()
+
+	log.Printf("[WS] instance %s listening for remote deliveries", wh.instanceID)
+
+	ch := wh.pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var relay dto.WSRelayMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &relay); err != nil {
+				log.Printf("[WS] invalid relay message on %s: %v", msg.Channel, err)
+				continue
+			}
+			// message dari instance sendiri sudah dikirim langsung di SendToUser
+			if relay.Origin == wh.instanceID {
+				continue
+			}
+
+			if relay.Room != "" {
+				wh.deliverRoom(relay.Room, relay.Data)
+				continue
+			}
+			wh.deliverLocal(relay.UserID, relay.ExceptRoom, relay.Data)
+		}
+	}
+}
+
+// syncChannel menyamakan subscription redis sebuah channel dengan kebutuhan koneksi lokal saat ini
+func (wh *webSocketService) syncChannel(channel string, wanted bool) {
+	wh.subMu.Lock()
+	defer wh.subMu.Unlock()
+
+	if wanted == wh.subscribed[channel] {
+		return
+	}
+
+	ctx := context.Background()
+	if wanted {
+		if err := wh.pubsub.Subscribe(ctx, channel); err != nil {
+			log.Printf("[WS] failed to subscribe %s: %v", channel, err)
+			return
+		}
+		wh.subscribed[channel] = true
+		return
+	}
+
+	if err := wh.pubsub.Unsubscribe(ctx, channel); err != nil {
+		log.Printf("[WS] failed to unsubscribe %s: %v", channel, err)
+		return
+	}
+	delete(wh.subscribed, channel)
+}
+
+// deliverLocal mengirim ke semua device user di instance ini, return jumlah yang berhasil.
+// exceptRoom diisi kalau koneksi yang sudah subscribe room tersebut menerima event lewat room
+func (wh *webSocketService) deliverLocal(userID, exceptRoom string, message []byte) int {
+	wh.mu.RLock()
+	clients := make([]*wsClient, 0, len(wh.connections[userID]))
+	for client := range wh.connections[userID] {
+		if _, ok := client.rooms[exceptRoom]; ok && exceptRoom != "" {
+			continue
+		}
+		clients = append(clients, client)
+	}
+	wh.mu.RUnlock()
+
+	// hanya masuk queue, write sebenarnya dikerjakan writePump masing-masing koneksi
+	delivered := 0
+	for _, client := range clients {
+		if client.enqueue(message) {
+			delivered++
+		}
+	}
+
+	return delivered
+}
+
+// withEventID menambahkan field event_id ke payload event tanpa mengubah bentuknya, jadi client lama
+// tetap menerima object yang sama. event ephemeral (id 0) dan payload bukan object dikirim apa adanya
+func withEventID(eventID int64, message []byte) []byte {
+	trimmed := bytes.TrimSpace(message)
+	if eventID <= 0 || len(trimmed) < 2 || trimmed[0] != '{' {
+		return message
+	}
+
+	field := `"event_id":` + strconv.FormatInt(eventID, 10)
+	body := bytes.TrimSpace(trimmed[1:])
+	data := make([]byte, 0, len(trimmed)+len(field)+1)
+	data = append(data, '{')
+	data = append(data, field...)
+	if len(body) > 0 && body[0] != '}' {
+		data = append(data, ',')
+	}
+
+	return append(data, body...)
+}
+
+// record menyimpan event ke log semua penerima supaya bisa di-replay, return id event (0 kalau gagal)
+func (wh *webSocketService) record(userIDs []string, message []byte) int64 {
+	ctx := context.Background()
+
+	eventID, err := wh.eventLogRepo.NextEventID(ctx)
+	if err != nil {
+		log.Printf("[WS] %v", err)
+		return 0
+	}
+
+	if err := wh.eventLogRepo.AppendEvent(ctx, userIDs, dto.WSEventLogEntry{
+		ID:   eventID,
+		Data: message,
+	}); err != nil {
+		log.Printf("[WS] %v", err)
+		return 0
+	}
+
+	return eventID
+}
+
+// SendToUser mengirim event ke semua device user dan menyimpannya di log user. tetap return error
+// kalau user offline supaya pemanggil bisa fallback bikin notif, event-nya tetap bisa di-replay
+func (wh *webSocketService) SendToUser(userID string, message []byte) error {
+	eventID := wh.record([]string{userID}, message)
+
+	return wh.sendToUser(userID, withEventID(eventID, message))
+}
+
+// SendEphemeralToUser sama seperti SendToUser tapi tidak disimpan di log (contoh: presence)
+func (wh *webSocketService) SendEphemeralToUser(userID string, message []byte) error {
+	return wh.sendToUser(userID, message)
+}
+
+// Replay mengirim ulang event setelah lastEventID ke koneksi pemanggil sesuai urutan.
+// event yang masuk bersamaan dengan replay bisa diterima dua kali, client dedupe berdasarkan id
+func (wh *webSocketService) Replay(ctx context.Context, lastEventID int64) (*dto.WSResumeResponse, error) {
+	client, err := wh.clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return wh.replayTo(client, lastEventID)
+}
+
+func (wh *webSocketService) replayTo(client *wsClient, lastEventID int64) (*dto.WSResumeResponse, error) {
+	entries, err := wh.eventLogRepo.GetEventsAfter(context.Background(), client.userID, lastEventID)
+	if err != nil {
+		log.Printf("[WS] failed to replay events for %s: %v", client.userID, err)
+		return nil, err
+	}
+
+	res := &dto.WSResumeResponse{LastEventID: lastEventID}
+	for _, entry := range entries {
+		if !client.enqueue(withEventID(entry.ID, entry.Data)) {
+			break
+		}
+		res.Replayed++
+		res.LastEventID = entry.ID
+	}
+	log.Printf("[WS] replayed %d event to %s after %d", res.Replayed, client.userID, lastEventID)
+
+	return res, nil
+}
+
+func (wh *webSocketService) sendToUser(userID string, message []byte) error {
+	return wh.sendToUserExcept(userID, "", message)
+}
+
+func (wh *webSocketService) sendToUserExcept(userID, exceptRoom string, message []byte) error {
+	local := wh.deliverLocal(userID, exceptRoom, message)
+
+	// jumlah penerima publish = instance yang sedang memegang koneksi user (termasuk instance ini)
+	remote := int64(0)
+	data, _ := json.Marshal(dto.WSRelayMessage{
+		Origin:     wh.instanceID,
+		UserID:     userID,
+		ExceptRoom: exceptRoom,
+		Data:       message,
+	})
+	receivers, err := wh.redis.Publish(context.Background(), userChannel(userID), data).Result()
+	if err != nil {
+		log.Printf("[WS] failed to publish message for %s: %v", userID, err)
+	} else {
+		wh.subMu.Lock()
+		self := wh.subscribed[userChannel(userID)]
+		wh.subMu.Unlock()
+
+		remote = receivers
+		if self {
+			remote--
+		}
+	}
+
+	if local == 0 && remote <= 0 {
+		// user tidak online di instance manapun → biar Start() bisa fallback bikin notif
+		return fmt.Errorf("user %s not connected", userID)
+	}
+
+	log.Printf("[WS SEND] to userID: '%s' (%d local device, %d remote instance)", userID, local, remote)
+	return nil
+}
+
+// deliverRoom mengirim ke semua koneksi anggota room di instance ini
+func (wh *webSocketService) deliverRoom(room string, message []byte) int {
+	wh.mu.RLock()
+	clients := make([]*wsClient, 0, len(wh.rooms[room]))
+	for client := range wh.rooms[room] {
+		clients = append(clients, client)
+	}
+	wh.mu.RUnlock()
+
+	delivered := 0
+	for _, client := range clients {
+		if client.enqueue(message) {
+			delivered++
+		}
+	}
+
+	return delivered
+}
+
+// BroadcastToRoom mengirim message ke semua koneksi yang subscribe room dalam satu publish.
+// userIDs adalah peserta yang log-nya ikut dicatat untuk replay, nil untuk event ephemeral (contoh: typing).
+// koneksi peserta yang belum subscribe room tetap menerima lewat channel user seperti sebelum ada room
+func (wh *webSocketService) BroadcastToRoom(room string, userIDs []string, message []byte) error {
+	eventID := int64(0)
+	if len(userIDs) > 0 {
+		eventID = wh.record(userIDs, message)
+	}
+	message = withEventID(eventID, message)
+
+	local := wh.deliverRoom(room, message)
+
+	data, _ := json.Marshal(dto.WSRelayMessage{
+		Origin: wh.instanceID,
+		Room:   room,
+		Data:   message,
+	})
+	if err := wh.redis.Publish(context.Background(), roomChannel(room), data).Err(); err != nil {
+		log.Printf("[WS] failed to publish message for room %s: %v", room, err)
+		return fmt.Errorf("failed publish to room %s: %w", room, err)
+	}
+
+	// peserta offline bukan error, event-nya sudah ada di log untuk replay
+	for _, userID := range userIDs {
+		_ = wh.sendToUserExcept(userID, room, message)
+	}
+
+	log.Printf("[WS BROADCAST] to room: '%s' (%d local connection)", room, local)
 	return nil
 }
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWithEventID(t *testing.T) {
+	tests := []struct {
+		name    string
+		eventID int64
+		message string
+		want    string
+	}{
+		{name: "object", eventID: 7, message: `{"event":"message"}`, want: `{"event_id":7,"event":"message"}`},
+		{name: "empty object", eventID: 7, message: `{}`, want: `{"event_id":7}`},
+		{name: "empty object with spaces", eventID: 7, message: ` { } `, want: `{"event_id":7}`},
+		{name: "leading whitespace in body", eventID: 3, message: "{\n  \"a\": 1\n}", want: "{\"event_id\":3,\"a\": 1\n}"},
+		{name: "ephemeral event unchanged", eventID: 0, message: `{"event":"typing_started"}`, want: `{"event":"typing_started"}`},
+		{name: "negative id unchanged", eventID: -1, message: `{"a":1}`, want: `{"a":1}`},
+		{name: "array unchanged", eventID: 7, message: `[1,2]`, want: `[1,2]`},
+		{name: "not json unchanged", eventID: 7, message: `ping`, want: `ping`},
+		{name: "empty unchanged", eventID: 7, message: ``, want: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(withEventID(tt.eventID, []byte(tt.message)))
+			if got != tt.want {
+				t.Fatalf("withEventID(%d, %q) = %q, want %q", tt.eventID, tt.message, got, tt.want)
+			}
+			if tt.eventID > 0 && json.Valid([]byte(tt.message)) && !json.Valid([]byte(got)) {
+				t.Errorf("withEventID(%d, %q) produced invalid json %q", tt.eventID, tt.message, got)
+			}
+		})
+	}
+}